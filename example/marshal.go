@@ -86,25 +86,26 @@ func main() {
 
 	// Output:
 	// [
-	// 	{
-	// 	  "prefer_go": true,
-	// 	  "tag": "local-dns",
-	// 	  "type": "local"
-	// 	},
-	// 	{
-	// 	  "server": "1.1.1.1",
-	// 	  "server_port": 53,
-	// 	  "tag": "udp-dns",
-	// 	  "type": "udp"
-	// 	},
-	// 	{
-	// 	  "type": "tls"
-	// 	  "server": "dns.google",
-	// 	  "server_port": 853,
-	// 	  "tag": "tls-dns",
-	// 	  "tls": {
-	// 	     "sni": "dns.google"
-	// 	  },
-	// 	}
+	//   {
+	//     "type": "local",
+	//     "tag": "local-dns",
+	//     "prefer_go": true
+	//   },
+	//   {
+	//     "type": "udp",
+	//     "tag": "udp-dns",
+	//     "server": "1.1.1.1",
+	//     "server_port": 53,
+	//     "timeout": 10000000000
+	//   },
+	//   {
+	//     "type": "tls",
+	//     "tag": "tls-dns",
+	//     "server": "dns.google",
+	//     "server_port": 853,
+	//     "tls": {
+	//       "sni": "dns.google"
+	//     }
+	//   }
 	// ]
 }
@@ -0,0 +1,116 @@
+package jsoninline
+
+import (
+	"errors"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+)
+
+// ConflictPolicy decides which field is kept when several fields of an
+// object, usually contributed by inline fields, encode to the same key.
+// A key keeps the position where it first appears; the policy only decides
+// which value is written there.
+// Entries of remain and inline maps never replace a field; only
+// ErrorOnConflict reports them.
+//
+// The policy applies when encoding. When decoding, every field sharing a
+// key receives its value, except under ErrorOnConflict.
+type ConflictPolicy int
+
+const (
+	// LastWins keeps the field that comes last in declaration order.
+	LastWins ConflictPolicy = iota
+	// FirstWins keeps the field that comes first in declaration order.
+	FirstWins
+	// ParentWins keeps the field declared closest to the encoded struct,
+	// that is, behind the fewest inline fields. Fields at the same depth
+	// fall back to LastWins.
+	ParentWins
+	// ErrorOnConflict fails with a *ConflictError naming both fields.
+	ErrorOnConflict
+)
+
+func (p ConflictPolicy) String() string {
+	switch p {
+	case LastWins:
+		return "LastWins"
+	case FirstWins:
+		return "FirstWins"
+	case ParentWins:
+		return "ParentWins"
+	case ErrorOnConflict:
+		return "ErrorOnConflict"
+	}
+	return "ConflictPolicy(" + strconv.Itoa(int(p)) + ")"
+}
+
+// ConflictError reports two fields of the same object that produce the
+// same JSON key. Fields are named by their Go path from the outermost
+// struct of the object, e.g. "UDP.ServerOptions.Server".
+type ConflictError struct {
+	Type   reflect.Type // struct type of the object
+	Key    string
+	Fields [2]string
+}
+
+func (e *ConflictError) Error() string {
+	return "jsoninline: key " + strconv.Quote(e.Key) + " of " + e.Type.String() +
+		" is produced by both " + e.Fields[0] + " and " + e.Fields[1]
+}
+
+// CheckConflicts reports every key that two fields of t, or of any struct
+// reachable from t, can produce in the same object. Fields belonging to
+// different variants of one discriminator never conflict. The result joins
+// one *ConflictError per key, or is nil.
+//
+// Keys contributed at runtime, by inline maps or inline values with their
+// own JSON codec, cannot be checked statically.
+func CheckConflicts(t reflect.Type) error {
+	return CheckConflictsOptions(t, nil)
+}
+
+// CheckConflictsOptions is like CheckConflicts but reads fields from the
+// tags named by opts. Nil opts means the defaults.
+func CheckConflictsOptions(t reflect.Type, opts *Options) error {
+	o := opts.options()
+	var errs []error
+	if err := checkConflicts(t, o.tags(), map[reflect.Type]bool{}, &errs); err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+func checkConflicts(t reflect.Type, tags tagNames, visited map[reflect.Type]bool, errs *[]error) error {
+	for {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+			continue
+		}
+		break
+	}
+	if t.Kind() != reflect.Struct || hasCodec(t) || visited[t] {
+		return nil
+	}
+	visited[t] = true
+
+	fs, err := cachedTypeFields(t, tags)
+	if err != nil {
+		return err
+	}
+	for _, key := range slices.Sorted(maps.Keys(fs.keys)) {
+		if a, b, ok := conflict(fs.keys[key]); ok {
+			*errs = append(*errs, &ConflictError{Type: t, Key: key, Fields: [2]string{a.path, b.path}})
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(fs.keys)) {
+		for _, src := range fs.keys[key] {
+			if err := checkConflicts(src.typ, tags, visited, errs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
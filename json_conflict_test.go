@@ -0,0 +1,127 @@
+package jsoninline_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hydrz/jsoninline"
+)
+
+type ParentCity struct {
+	Name string `json:"name"`
+	USA  *USA   `json:",inline"`
+	City string `json:"city"`
+}
+
+// TestConflictPolicy ensures each policy picks the expected value for a key
+// produced by several fields.
+func TestConflictPolicy(t *testing.T) {
+	both := User{ID: 1, China: &China{City: "Shenzhen"}, USA: &USA{City: "Austin"}}
+	parent := ParentCity{Name: "p", USA: &USA{City: "Austin"}, City: "Parent"}
+
+	tests := []struct {
+		policy jsoninline.ConflictPolicy
+		value  any
+		want   string
+	}{
+		{jsoninline.LastWins, both, `{"id":1,"name":"","email":"","city":"Austin"}`},
+		{jsoninline.FirstWins, both, `{"id":1,"name":"","email":"","city":"Shenzhen"}`},
+		{jsoninline.ParentWins, both, `{"id":1,"name":"","email":"","city":"Austin"}`},
+		{jsoninline.LastWins, parent, `{"name":"p","city":"Parent"}`},
+		{jsoninline.FirstWins, parent, `{"name":"p","city":"Austin"}`},
+		{jsoninline.ParentWins, parent, `{"name":"p","city":"Parent"}`},
+	}
+	for _, tt := range tests {
+		im := &jsoninline.InlineMarshaler{V: tt.value, Options: &jsoninline.Options{ConflictPolicy: tt.policy}}
+		b, err := json.Marshal(im)
+		if err != nil {
+			t.Fatalf("%v: marshal failed: %v", tt.policy, err)
+		}
+		if string(b) != tt.want {
+			t.Errorf("%v: unexpected output\n got: %s\nwant: %s", tt.policy, b, tt.want)
+		}
+	}
+}
+
+// TestConflictError ensures ErrorOnConflict reports both Go field paths when
+// encoding and decoding.
+func TestConflictError(t *testing.T) {
+	opts := &jsoninline.Options{ConflictPolicy: jsoninline.ErrorOnConflict}
+	want := [2]string{"China.City", "USA.City"}
+
+	u := User{ID: 1, China: &China{City: "Shenzhen"}, USA: &USA{City: "Austin"}}
+	_, err := json.Marshal(&jsoninline.InlineMarshaler{V: u, Options: opts})
+	var ce *jsoninline.ConflictError
+	if !errors.As(err, &ce) {
+		t.Fatalf("expected ConflictError from marshal, got %v", err)
+	}
+	if ce.Key != "city" || ce.Fields != want {
+		t.Fatalf("unexpected conflict: %+v", ce)
+	}
+
+	// without the second city the object has no conflict
+	u.USA.City = ""
+	if _, err := json.Marshal(&jsoninline.InlineMarshaler{V: u, Options: opts}); err != nil {
+		t.Fatalf("unexpected marshal error: %v", err)
+	}
+
+	var decoded User
+	err = json.Unmarshal([]byte(`{"id":1,"city":"X"}`), &jsoninline.InlineMarshaler{V: &decoded, Options: opts})
+	if !errors.As(err, &ce) {
+		t.Fatalf("expected ConflictError from unmarshal, got %v", err)
+	}
+	if ce.Key != "city" || ce.Fields != want {
+		t.Fatalf("unexpected conflict: %+v", ce)
+	}
+}
+
+// TestCheckConflicts ensures the static check reports keys shared by
+// fields that can be present together, and ignores exclusive variants.
+func TestCheckConflicts(t *testing.T) {
+	err := jsoninline.CheckConflicts(reflect.TypeFor[[]User]())
+	if err == nil {
+		t.Fatalf("expected conflicts for User")
+	}
+	var keys []string
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var ce *jsoninline.ConflictError
+		if !errors.As(e, &ce) {
+			t.Fatalf("unexpected error type %T", e)
+		}
+		keys = append(keys, ce.Key)
+	}
+	if want := []string{"bar_field", "city", "foo_field"}; !reflect.DeepEqual(keys, want) {
+		t.Fatalf("expected conflicting keys %v, got %v (%v)", want, keys, err)
+	}
+
+	if err := jsoninline.CheckConflicts(reflect.TypeFor[DNSServerOption]()); err != nil {
+		t.Fatalf("expected variants not to conflict, got %v", err)
+	}
+}
+
+type CfgServer struct {
+	Name string   `json:"name" cfg:"name"`
+	Meta *CfgMeta `json:"meta" cfg:",inline" dir:"inline"`
+}
+
+type CfgMeta struct {
+	Label string `json:"name" cfg:"name"`
+}
+
+// TestCheckConflictsOptions ensures the static check reads fields from the
+// configured tags.
+func TestCheckConflictsOptions(t *testing.T) {
+	typ := reflect.TypeFor[CfgServer]()
+	if err := jsoninline.CheckConflicts(typ); err != nil {
+		t.Fatalf("expected no conflicts with the default tags, got %v", err)
+	}
+	for _, opts := range []*jsoninline.Options{{TagName: "cfg"}, {DirectiveTagName: "dir"}} {
+		err := jsoninline.CheckConflictsOptions(typ, opts)
+		var ce *jsoninline.ConflictError
+		if !errors.As(err, &ce) || ce.Key != "name" || ce.Fields != [2]string{"Name", "Meta.Label"} {
+			t.Fatalf("%+v: expected conflict on name, got %v", opts, err)
+		}
+	}
+}
@@ -0,0 +1,567 @@
+package jsoninline
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+)
+
+// decodeState holds the options and progress of a decode call.
+type decodeState struct {
+	opts    Options
+	input   []byte      // the top-level value
+	ix      *valueIndex // index of input, see index
+	path    []pathElem  // path of the value being decoded
+	id      *decodeID   // identifies the errors located by this decode, see pop
+	unknown []string    // JSON paths of keys no field consumed
+}
+
+// objectState is a JSON object being decoded into a struct and its inline
+// fields.
+type objectState struct {
+	data    rawValue
+	list    []rawMember         // members in document order
+	members map[string]rawValue // members by key, the last one of duplicates
+
+	// taken maps each key to the Go path of the field that decoded it.
+	// It is only tracked under ErrorOnConflict.
+	taken map[string]string
+
+	// consumed records the keys claimed by some field. It is only tracked
+	// when unknown fields are disallowed or the struct has remain fields.
+	consumed map[string]bool
+	all      bool // every key is claimed, by an inline value with its own codec
+
+	remain []remainField // remain fields receiving the unclaimed keys
+
+	// renamed maps the field names that members were stored under by
+	// case-insensitive matching to the keys found in the object.
+	renamed map[string]string
+}
+
+// remainField is a remain field found while decoding an object.
+type remainField struct {
+	v      reflect.Value
+	goPath string
+}
+
+// key returns the key of the object stored under name in members.
+func (obj *objectState) key(name string) string {
+	if key, ok := obj.renamed[name]; ok {
+		return key
+	}
+	return name
+}
+
+func unmarshal(data []byte, p any, opts Options) error {
+	v := reflect.ValueOf(p)
+	if v.Kind() != reflect.Ptr {
+		return errors.New("jsoninline: V must be a pointer")
+	}
+	if v.IsNil() {
+		return json.Unmarshal(data, p)
+	}
+	if !json.Valid(data) {
+		// report the syntax error as encoding/json does
+		return json.Unmarshal(data, new(json.RawMessage))
+	}
+	d := &decodeState{opts: opts, input: data}
+	if err := d.value(rawValue{data: data}, v.Elem()); err != nil {
+		return err
+	}
+	if len(d.unknown) > 0 {
+		slices.Sort(d.unknown)
+		return &UnknownFieldsError{Paths: d.unknown}
+	}
+	return nil
+}
+
+var (
+	unmarshalerType     = reflect.TypeFor[json.Unmarshaler]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// value decodes r into the addressable value v, applying inline semantics
+// to every struct it reaches through pointers, interfaces holding non-nil
+// pointers, slices, arrays and maps. Values implementing json.Unmarshaler
+// or encoding.TextUnmarshaler are left to encoding/json. Like
+// encoding/json, it decodes into the existing value: fields absent from r
+// keep their values, non-nil pointers are followed and slice elements are
+// reused.
+func (d *decodeState) value(r rawValue, v reflect.Value) error {
+	data := r.data
+	// Like encoding/json, ignore the methods of values that cannot be
+	// used as interfaces, such as embedded structs of unexported types.
+	if pt := reflect.PointerTo(v.Type()); v.CanInterface() && pt.Implements(inlineTargeterType) {
+		p, opts := v.Addr().Interface().(inlineTargeter).inlineTarget()
+		return d.wrapped(r, p, opts)
+	}
+	if pt := reflect.PointerTo(v.Type()); v.CanInterface() && (pt.Implements(unmarshalerType) || pt.Implements(textUnmarshalerType)) {
+		return d.stdlib(data, v.Addr().Interface())
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if isNull(data) {
+			v.SetZero()
+			return nil
+		}
+		if v.IsNil() {
+			if !v.CanSet() {
+				return errors.New("jsoninline: cannot set embedded pointer to unexported struct " + v.Type().Elem().String())
+			}
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.value(r, v.Elem())
+
+	case reflect.Interface:
+		// Like encoding/json, decode into a non-nil pointer held by the
+		// interface, unless null would only clear the pointer.
+		if v.IsNil() {
+			break
+		}
+		if e := v.Elem(); e.Kind() == reflect.Ptr && !e.IsNil() && (!isNull(data) || e.Elem().Kind() == reflect.Ptr) {
+			return d.value(r, e.Elem())
+		}
+
+	case reflect.Slice:
+		if isNull(data) {
+			v.SetZero()
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		raws, err := d.elements(r, v.Type())
+		if err != nil {
+			return err
+		}
+		switch n := len(raws); {
+		case v.IsNil() || v.Cap() < n:
+			slice := reflect.MakeSlice(v.Type(), n, n)
+			reflect.Copy(slice, v)
+			v.Set(slice)
+		default:
+			v.SetLen(n)
+		}
+		for i, raw := range raws {
+			if err := d.element(raw, v.Index(i), i); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Array:
+		if isNull(data) {
+			return nil
+		}
+		raws, err := d.elements(r, v.Type())
+		if err != nil {
+			return err
+		}
+		// As in encoding/json, extra elements are dropped and missing
+		// ones are zeroed.
+		for i := 0; i < v.Len(); i++ {
+			if i >= len(raws) {
+				v.Index(i).SetZero()
+				continue
+			}
+			if err := d.element(raws[i], v.Index(i), i); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Map:
+		kt := v.Type().Key()
+		switch kt.Kind() {
+		case reflect.String,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		default:
+			if !reflect.PointerTo(kt).Implements(textUnmarshalerType) {
+				// let encoding/json report the unsupported key type
+				return d.stdlib(data, v.Addr().Interface())
+			}
+		}
+		if isNull(data) {
+			v.SetZero()
+			return nil
+		}
+		ms, err := d.members(r, v.Type())
+		if err != nil {
+			return err
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(v.Type(), len(ms)))
+		}
+		et := v.Type().Elem()
+		for _, m := range ms {
+			d.path = append(d.path, pathElem{key: m.key, index: -1})
+			kv, err := mapKeyValue(m.key, kt)
+			if err := d.pop(err, m.value.off); err != nil {
+				return err
+			}
+			elem := reflect.New(et).Elem()
+			if err := d.member(m.value, elem, m.key, ""); err != nil {
+				return err
+			}
+			v.SetMapIndex(kv, elem)
+		}
+		return nil
+
+	case reflect.Struct:
+		if isNull(data) {
+			return nil
+		}
+		// struct: parse top-level map and populate fields, handling ",inline" tags
+		ms, err := d.members(r, v.Type())
+		if err != nil {
+			return err
+		}
+		obj := &objectState{data: r, list: ms, members: make(map[string]rawValue, len(ms))}
+		for _, m := range ms {
+			obj.members[m.key] = m.value
+		}
+		if d.opts.ConflictPolicy == ErrorOnConflict {
+			obj.taken = make(map[string]string)
+		}
+		fs, err := cachedTypeFields(v.Type(), d.opts.tags())
+		if err != nil {
+			return err
+		}
+		if !d.opts.CaseSensitive {
+			obj.foldKeys(fs)
+		}
+		if d.opts.DisallowUnknownFields || fs.remain {
+			obj.consumed = make(map[string]bool, len(obj.members))
+		}
+		if err := d.structFields(obj, v, v.Type(), ""); err != nil {
+			return err
+		}
+		if err := d.fillRemain(obj); err != nil {
+			return err
+		}
+		d.checkUnknown(obj)
+		return nil
+	}
+
+	return d.stdlib(data, v.Addr().Interface())
+}
+
+// parseRaw unmarshals data into p, which holds the raw elements or
+// members of a value of type t, reporting JSON of the wrong kind as an
+// *json.UnmarshalTypeError naming t.
+func parseRaw(data []byte, p any, t reflect.Type) error {
+	err := json.Unmarshal(data, p)
+	if te, ok := err.(*json.UnmarshalTypeError); ok {
+		return &json.UnmarshalTypeError{Value: te.Value, Type: t, Offset: te.Offset}
+	}
+	return err
+}
+
+// mapKeyValue converts the object key k to a map key of type kt. Like
+// encoding/json, it prefers an UnmarshalText method over the string kind.
+func mapKeyValue(k string, kt reflect.Type) (reflect.Value, error) {
+	if reflect.PointerTo(kt).Implements(textUnmarshalerType) {
+		kv := reflect.New(kt)
+		if err := kv.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(k)); err != nil {
+			return reflect.Value{}, err
+		}
+		return kv.Elem(), nil
+	}
+	switch kt.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(k).Convert(kt), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(k, 10, 64)
+		if err != nil || kt.OverflowInt(n) {
+			return reflect.Value{}, &json.UnmarshalTypeError{Value: "number " + k, Type: kt}
+		}
+		return reflect.ValueOf(n).Convert(kt), nil
+	default:
+		n, err := strconv.ParseUint(k, 10, 64)
+		if err != nil || kt.OverflowUint(n) {
+			return reflect.Value{}, &json.UnmarshalTypeError{Value: "number " + k, Type: kt}
+		}
+		return reflect.ValueOf(n).Convert(kt), nil
+	}
+}
+
+// wrapped decodes data into the target p of a wrapper, using opts if not
+// nil.
+func (d *decodeState) wrapped(r rawValue, p any, opts *Options) error {
+	if p == nil {
+		return errors.New("jsoninline: nil target for UnmarshalJSON")
+	}
+	if opts != nil {
+		saved := d.opts
+		d.opts = *opts
+		defer func() { d.opts = saved }()
+	}
+	v := reflect.ValueOf(p)
+	if v.Kind() != reflect.Ptr {
+		return errors.New("jsoninline: V must be a pointer")
+	}
+	if v.IsNil() {
+		return d.stdlib(r.data, p)
+	}
+	return d.value(r, v.Elem())
+}
+
+// element decodes the array element at index i.
+func (d *decodeState) element(r rawValue, v reflect.Value, i int) error {
+	d.path = append(d.path, pathElem{index: i})
+	return d.pop(d.value(r, v), r.off)
+}
+
+// member decodes the value of the object key into v, the struct field at
+// goPath or, if goPath is empty, a map element.
+func (d *decodeState) member(r rawValue, v reflect.Value, key, goPath string) error {
+	d.path = append(d.path, pathElem{key: key, index: -1, goPath: goPath})
+	return d.pop(d.value(r, v), r.off)
+}
+
+// pop leaves the value that element or member entered, which starts off
+// bytes into the input, locating err in it unless an inner value of this
+// decode already did.
+func (d *decodeState) pop(err error, off int) error {
+	if err != nil {
+		if d.id == nil {
+			d.id = new(decodeID)
+		}
+		if fe, ok := err.(*FieldError); !ok || fe.origin != d.id {
+			err = fieldError(err, d.path, int64(off), d.id)
+		}
+	}
+	d.path = d.path[:len(d.path)-1]
+	return err
+}
+
+// quotedMember is like member for the scalar v of a field with the string
+// option, which expects its JSON encoding inside a JSON string. As in
+// encoding/json, null and values with their own codec are decoded as
+// usual.
+func (d *decodeState) quotedMember(r rawValue, v reflect.Value, key, goPath string) error {
+	t := v.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if isNull(r.data) || reflect.PointerTo(t).Implements(unmarshalerType) || reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return d.member(r, v, key, goPath)
+	}
+	d.path = append(d.path, pathElem{key: key, index: -1, goPath: goPath})
+	var s string
+	err := json.Unmarshal(r.data, &s)
+	if err == nil {
+		// a scalar, which is never split and so needs no offset of its own
+		err = d.value(rawValue{data: []byte(s), off: r.off}, v)
+	}
+	if err != nil {
+		err = fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal %q into %v", r.data, v.Type())
+	}
+	return d.pop(err, r.off)
+}
+
+// stdlib decodes data into p with encoding/json, carrying over the
+// options it understands.
+func (d *decodeState) stdlib(data []byte, p any) error {
+	if !d.opts.DisallowUnknownFields && !d.opts.UseNumber {
+		return json.Unmarshal(data, p)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if d.opts.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if d.opts.UseNumber {
+		dec.UseNumber()
+	}
+	return dec.Decode(p)
+}
+
+// structFields populates the struct v from the members of obj. Every inline
+// field selected by the discriminator receives the whole object. t is the
+// struct type of the object and prefix the Go path of v within it.
+func (d *decodeState) structFields(obj *objectState, v reflect.Value, t reflect.Type, prefix string) error {
+	fs, err := cachedTypeFields(v.Type(), d.opts.tags())
+	if err != nil {
+		return err
+	}
+
+	var selected string
+	if fs.discriminator >= 0 {
+		disc := &fs.list[fs.discriminator]
+		if raw, ok := obj.members[disc.name]; ok {
+			selected = rawDiscriminatorValue(raw.data)
+		} else if dv, ok := fieldByIndex(v, disc.index); ok {
+			// keep the variant of the value decoded into
+			selected = discriminatorValue(dv, disc, d.opts)
+		}
+		if d.opts.RequireVariant {
+			if err := fs.checkVariant(v.Type(), selected); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i := range fs.list {
+		f := &fs.list[i]
+		// only the variant chosen by the discriminator is populated
+		if f.variant != "" && f.variant != selected {
+			continue
+		}
+
+		if f.inline {
+			fv, err := fieldByIndexAlloc(v, f.index)
+			if err != nil {
+				return err
+			}
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					fv.Set(reflect.New(fv.Type().Elem()))
+				}
+				fv = fv.Elem()
+			}
+			if f.inlineType != nil {
+				if err := d.structFields(obj, fv, t, prefix+f.goPath+"."); err != nil {
+					return err
+				}
+				continue
+			}
+			obj.all = true
+			if err := d.value(obj.data, fv); err != nil {
+				return err
+			}
+			continue
+		}
+		if f.remain {
+			fv, err := fieldByIndexAlloc(v, f.index)
+			if err != nil {
+				return err
+			}
+			obj.remain = append(obj.remain, remainField{v: fv, goPath: prefix + f.goPath})
+			continue
+		}
+
+		raw, ok := obj.members[f.name]
+		if !ok {
+			// not present in JSON; keep the current value
+			continue
+		}
+		if obj.taken != nil {
+			path := prefix + f.goPath
+			if prev, ok := obj.taken[f.name]; ok {
+				return &ConflictError{Type: t, Key: f.name, Fields: [2]string{prev, path}}
+			}
+			obj.taken[f.name] = path
+		}
+		if obj.consumed != nil {
+			obj.consumed[f.name] = true
+		}
+		fv, err := fieldByIndexAlloc(v, f.index)
+		if err != nil {
+			return err
+		}
+		if f.quoted {
+			err = d.quotedMember(raw, fv, obj.key(f.name), prefix+f.goPath)
+		} else {
+			err = d.member(raw, fv, obj.key(f.name), prefix+f.goPath)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fillRemain stores the keys of obj that no field consumed into every
+// selected remain field, which then claim them.
+func (d *decodeState) fillRemain(obj *objectState) error {
+	if len(obj.remain) == 0 {
+		return nil
+	}
+	var keys []string
+	for key := range obj.members {
+		if !obj.consumed[key] {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	for _, r := range obj.remain {
+		fv := r.v
+		if len(keys) > 0 && fv.IsNil() {
+			fv.Set(reflect.MakeMapWithSize(fv.Type(), len(keys)))
+		}
+		kt, et := fv.Type().Key(), fv.Type().Elem()
+		for _, name := range keys {
+			key := obj.key(name)
+			elem := reflect.New(et).Elem()
+			if err := d.member(obj.members[name], elem, key, r.goPath+"["+strconv.Quote(key)+"]"); err != nil {
+				return err
+			}
+			fv.SetMapIndex(reflect.ValueOf(key).Convert(kt), elem)
+		}
+	}
+	for _, key := range keys {
+		obj.consumed[key] = true
+	}
+	return nil
+}
+
+// foldKeys stores the members whose keys are no field name of fs under
+// the name of a field that matches them under case folding, as
+// encoding/json does. When several keys match the same field, the last one
+// in the object wins.
+func (obj *objectState) foldKeys(fs *structFields) {
+	needed := false
+	for key := range obj.members {
+		if _, ok := fs.keys[key]; !ok {
+			if _, ok := fs.folded[foldName(key)]; ok {
+				needed = true
+				break
+			}
+		}
+	}
+	if !needed {
+		return
+	}
+
+	members := make(map[string]rawValue, len(obj.members))
+	obj.renamed = make(map[string]string)
+	for _, m := range obj.list {
+		key, name := m.key, m.key
+		if _, ok := fs.keys[key]; !ok {
+			if folded, ok := fs.folded[foldName(key)]; ok {
+				name = folded
+			}
+		}
+		members[name] = m.value
+		if name != key {
+			obj.renamed[name] = key
+		} else {
+			delete(obj.renamed, name)
+		}
+	}
+	obj.members = members
+}
+
+// checkUnknown records the keys of obj that no field consumed.
+func (d *decodeState) checkUnknown(obj *objectState) {
+	if obj.consumed == nil || obj.all {
+		return
+	}
+	for name := range obj.members {
+		if !obj.consumed[name] {
+			d.unknown = append(d.unknown, formatJSONPath(append(d.path, pathElem{key: obj.key(name), index: -1})))
+		}
+	}
+}
+
+func isNull(data []byte) bool {
+	return bytes.Equal(bytes.TrimSpace(data), []byte("null"))
+}
@@ -0,0 +1,437 @@
+package jsoninline_test
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/hydrz/jsoninline"
+)
+
+type DNSConfig struct {
+	Servers []DNSServerOption `json:"servers"`
+	Hosts   map[string]User   `json:"hosts,omitempty"`
+}
+
+// TestDisallowUnknownFields ensures strict decoding accepts keys consumed
+// by inline fields and reports every other key with its JSON path.
+func TestDisallowUnknownFields(t *testing.T) {
+	strict := &jsoninline.Options{DisallowUnknownFields: true}
+
+	var u User
+	data := `{"id":1,"city":"Austin","state":"TX","foo_field":"f","bar_field":"b"}`
+	if err := json.Unmarshal([]byte(data), &jsoninline.InlineMarshaler{V: &u, Options: strict}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data = `{
+        "servers": [
+            {"type":"udp","tag":"a","server":"1.1.1.1","server_port":53},
+            {"type":"local","tag":"b","server":"1.1.1.1","prefer_go":true},
+            {"type":"tls","tag":"c","sni":"x","sever":"typo"}
+        ],
+        "hosts": {"home": {"id":2,"zip":"00000"}, "a b": {"nick":"n"}},
+        "version": 2
+    }`
+	var cfg DNSConfig
+	err := json.Unmarshal([]byte(data), &jsoninline.InlineMarshaler{V: &cfg, Options: strict})
+	var ue *jsoninline.UnknownFieldsError
+	if !errors.As(err, &ue) {
+		t.Fatalf("expected UnknownFieldsError, got %v", err)
+	}
+	want := []string{"hosts.home.zip", `hosts["a b"].nick`, "servers[1].server", "servers[2].sever", "version"}
+	if !slices.Equal(ue.Paths, want) {
+		t.Fatalf("unexpected unknown fields\n got: %q\nwant: %q", ue.Paths, want)
+	}
+
+	// the same input decodes without complaint by default
+	if err := json.Unmarshal([]byte(data), &jsoninline.InlineMarshaler{V: &cfg}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+type PluginConfig struct {
+	Name  string                     `json:"name"`
+	China *China                     `json:",inline"`
+	Extra map[string]json.RawMessage `json:",remain"`
+}
+
+// TestRemain ensures a remain map collects the keys no field consumes and
+// writes them back when encoding.
+func TestRemain(t *testing.T) {
+	data := `{"name":"p","timeout":30,"city":"Shenzhen","tags":["a", "b"]}`
+	opts := &jsoninline.Options{DisallowUnknownFields: true}
+
+	var cfg PluginConfig
+	if err := json.Unmarshal([]byte(data), &jsoninline.InlineMarshaler{V: &cfg, Options: opts}); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if cfg.Name != "p" || cfg.China == nil || cfg.China.City != "Shenzhen" {
+		t.Fatalf("unexpected fields: %+v", cfg)
+	}
+	if len(cfg.Extra) != 2 || string(cfg.Extra["timeout"]) != "30" || string(cfg.Extra["tags"]) != `["a", "b"]` {
+		t.Fatalf("unexpected remain: %q", cfg.Extra)
+	}
+
+	b, err := json.Marshal(jsoninline.V(cfg))
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	want := `{"name":"p","city":"Shenzhen","tags":["a","b"],"timeout":30}`
+	if string(b) != want {
+		t.Fatalf("unexpected output\n got: %s\nwant: %s", b, want)
+	}
+
+	// a remain key shadowing a field never replaces it, and is a conflict
+	// under ErrorOnConflict
+	cfg.Extra["name"] = json.RawMessage(`"clobber"`)
+	for _, policy := range []jsoninline.ConflictPolicy{jsoninline.LastWins, jsoninline.FirstWins, jsoninline.ParentWins} {
+		b, err := json.Marshal(&jsoninline.InlineMarshaler{V: cfg, Options: &jsoninline.Options{ConflictPolicy: policy}})
+		if err != nil {
+			t.Fatalf("marshal failed: %v", err)
+		}
+		if string(b) != want {
+			t.Fatalf("policy %v: unexpected output\n got: %s\nwant: %s", policy, b, want)
+		}
+	}
+	_, err = json.Marshal(&jsoninline.InlineMarshaler{V: cfg, Options: &jsoninline.Options{ConflictPolicy: jsoninline.ErrorOnConflict}})
+	var ce *jsoninline.ConflictError
+	if !errors.As(err, &ce) {
+		t.Fatalf("expected ConflictError, got %v", err)
+	}
+	if ce.Fields != [2]string{"Name", `Extra["name"]`} {
+		t.Fatalf("unexpected conflict: %+v", ce)
+	}
+}
+
+type Labeled struct {
+	ID     int               `json:"id"`
+	USA    *USA              `json:",inline"`
+	Labels map[string]string `json:",inline"`
+}
+
+// TestInlineMap ensures an inline map receives only the keys of its parent
+// that no field consumes and that its entries are conflict checked when
+// encoding.
+func TestInlineMap(t *testing.T) {
+	var l Labeled
+	data := `{"id":1,"state":"TX","env":"prod","team":"dns"}`
+	if err := json.Unmarshal([]byte(data), jsoninline.V(&l)); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	want := map[string]string{"env": "prod", "team": "dns"}
+	if l.ID != 1 || l.USA == nil || l.USA.State != "TX" || !maps.Equal(l.Labels, want) {
+		t.Fatalf("unexpected value: %+v", l)
+	}
+
+	b, err := json.Marshal(jsoninline.V(l))
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if string(b) != `{"id":1,"state":"TX","env":"prod","team":"dns"}` {
+		t.Fatalf("unexpected output: %s", b)
+	}
+
+	l.Labels["id"] = "2"
+	b, err = json.Marshal(&jsoninline.InlineMarshaler{V: l, Options: &jsoninline.Options{ConflictPolicy: jsoninline.ParentWins}})
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if string(b) != `{"id":1,"state":"TX","env":"prod","team":"dns"}` {
+		t.Fatalf("unexpected output: %s", b)
+	}
+	_, err = json.Marshal(&jsoninline.InlineMarshaler{V: l, Options: &jsoninline.Options{ConflictPolicy: jsoninline.ErrorOnConflict}})
+	var ce *jsoninline.ConflictError
+	if !errors.As(err, &ce) || ce.Fields != [2]string{"ID", `Labels["id"]`} {
+		t.Fatalf("expected conflict on id, got %v", err)
+	}
+
+	// values that do not fit the map's element type are errors
+	var ints struct {
+		Name   string         `json:"name"`
+		Counts map[string]int `json:",inline"`
+	}
+	if err := json.Unmarshal([]byte(`{"name":"n","a":1}`), jsoninline.V(&ints)); err != nil || ints.Counts["a"] != 1 {
+		t.Fatalf("unexpected result %v: %+v", err, ints)
+	}
+	if err := json.Unmarshal([]byte(`{"name":"n","a":"x"}`), jsoninline.V(&ints)); err == nil {
+		t.Fatal("expected an error for a string in map[string]int")
+	}
+}
+
+type FoldPair struct {
+	X string `json:"ab"`
+	Y string `json:"AB"`
+}
+
+// TestCaseInsensitiveKeys ensures keys match field names under case
+// folding like encoding/json, preferring exact matches, unless
+// CaseSensitive is set.
+func TestCaseInsensitiveKeys(t *testing.T) {
+	data := `{"ID":1,"Name":"Alice","CITY":"Austin","State":"TX","extra":true}`
+
+	var u User
+	if err := json.Unmarshal([]byte(data), jsoninline.V(&u)); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	var want User
+	if err := json.Unmarshal([]byte(data), &want); err != nil {
+		t.Fatalf("encoding/json unmarshal failed: %v", err)
+	}
+	if u.ID != want.ID || u.Name != want.Name || u.USA == nil || u.USA.City != "Austin" || u.USA.State != "TX" {
+		t.Fatalf("unexpected value: %+v %+v", u, u.USA)
+	}
+
+	// the last of several matching keys wins, as in encoding/json
+	for _, data := range []string{`{"name":"a","NAME":"b"}`, `{"NAME":"b","name":"a"}`} {
+		var got, want User
+		if err := json.Unmarshal([]byte(data), jsoninline.V(&got)); err != nil {
+			t.Fatalf("unmarshal failed: %v", err)
+		}
+		if err := json.Unmarshal([]byte(data), &want); err != nil {
+			t.Fatalf("encoding/json unmarshal failed: %v", err)
+		}
+		if got.Name != want.Name {
+			t.Errorf("%s: got name %q, want %q", data, got.Name, want.Name)
+		}
+	}
+
+	// a key folding to several field names goes to the first declared one
+	var fold, stdFold FoldPair
+	if err := json.Unmarshal([]byte(`{"Ab":"v"}`), jsoninline.V(&fold)); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if err := json.Unmarshal([]byte(`{"Ab":"v"}`), &stdFold); err != nil {
+		t.Fatalf("encoding/json unmarshal failed: %v", err)
+	}
+	if fold != stdFold || fold.X != "v" {
+		t.Errorf("got %+v, want %+v", fold, stdFold)
+	}
+
+	// unmatched keys keep their spelling
+	var cfg PluginConfig
+	if err := json.Unmarshal([]byte(`{"NAME":"p","Timeout":30}`), jsoninline.V(&cfg)); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if cfg.Name != "p" || string(cfg.Extra["Timeout"]) != "30" {
+		t.Fatalf("unexpected value: %+v", cfg)
+	}
+
+	opts := &jsoninline.Options{CaseSensitive: true, DisallowUnknownFields: true}
+	err := jsoninline.UnmarshalOptions([]byte(data), &u, opts)
+	var ue *jsoninline.UnknownFieldsError
+	if !errors.As(err, &ue) || !slices.Equal(ue.Paths, []string{"CITY", "ID", "Name", "State", "extra"}) {
+		t.Fatalf("expected every key but exact ones to be unknown, got %v", err)
+	}
+}
+
+// TestUnmarshalMerge ensures decoding overlays the JSON onto an existing
+// value, keeping absent fields, allocated inline pointers and the selected
+// variant.
+func TestUnmarshalMerge(t *testing.T) {
+	china := &China{City: "Shenzhen", Province: "Guangdong"}
+	u := User{ID: 1, Name: "Alice", Email: "alice@example.com", China: china}
+	if err := json.Unmarshal([]byte(`{"name":"Bob","city":"Guangzhou"}`), jsoninline.V(&u)); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if u.ID != 1 || u.Name != "Bob" || u.Email != "alice@example.com" {
+		t.Fatalf("unexpected fields: %+v", u)
+	}
+	if u.China != china || china.City != "Guangzhou" || china.Province != "Guangdong" {
+		t.Fatalf("inline pointer not reused: %+v", u.China)
+	}
+
+	// slice elements are decoded into the existing ones
+	users := []User{{ID: 1, Email: "a@example.com"}, {ID: 2}}
+	if err := json.Unmarshal([]byte(`[{"name":"A"}]`), jsoninline.V(&users)); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if len(users) != 1 || users[0].ID != 1 || users[0].Name != "A" || users[0].Email != "a@example.com" {
+		t.Fatalf("unexpected users: %+v", users)
+	}
+
+	// without a discriminator key the current one selects the variant
+	opt := DNSServerOption{Type: "udp", Tag: "dns", UDP: &UDPDNSServerOption{Server: "1.1.1.1", ServerPort: 53}}
+	if err := json.Unmarshal([]byte(`{"server_port":5353,"prefer_go":true}`), jsoninline.V(&opt)); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if opt.Type != "udp" || opt.UDP.Server != "1.1.1.1" || opt.UDP.ServerPort != 5353 || opt.Local.PreferGO {
+		t.Fatalf("unexpected value: %+v %+v", opt, opt.UDP)
+	}
+}
+
+type Host struct {
+	Name    string   `json:"name"`
+	Metrics *Metrics `json:",inline"`
+}
+
+type Metrics struct {
+	Load float64 `json:"load"`
+}
+
+type Custom struct {
+	A int `json:"a"`
+}
+
+func (c *Custom) UnmarshalJSON(data []byte) error {
+	type plain Custom
+	return jsoninline.UnmarshalOptions(data, (*plain)(c), nil)
+}
+
+type CustomList struct {
+	Items []Custom `json:"items"`
+}
+
+// TestFieldError ensures errors inside nested and inline values report
+// where they happened.
+func TestFieldError(t *testing.T) {
+	data := `{"servers": [
+        {"type":"local","tag":"a"},
+        {"type":"udp","tag":"b","server_port": "53"}
+    ]}`
+	var cfg DNSConfig
+	err := json.Unmarshal([]byte(data), jsoninline.V(&cfg))
+	var fe *jsoninline.FieldError
+	if !errors.As(err, &fe) {
+		t.Fatalf("expected FieldError, got %v", err)
+	}
+	if fe.JSONPath != "servers[1].server_port" || fe.GoPath != "Servers[1].UDP.ServerPort" {
+		t.Fatalf("unexpected paths %q %q", fe.JSONPath, fe.GoPath)
+	}
+	if want := int64(strings.Index(data, `"53"`)); fe.Offset != want {
+		t.Fatalf("unexpected offset %d, want %d", fe.Offset, want)
+	}
+	var te *json.UnmarshalTypeError
+	if !errors.As(err, &te) {
+		t.Fatalf("expected the UnmarshalTypeError to be wrapped, got %v", fe.Err)
+	}
+
+	// JSON of the wrong kind names the Go type it was decoded into
+	for _, tc := range []struct {
+		data, path, value string
+		typ               reflect.Type
+	}{
+		{`{"servers":{}}`, "servers", "object", reflect.TypeFor[[]DNSServerOption]()},
+		{`{"servers":[[]]}`, "servers[0]", "array", reflect.TypeFor[DNSServerOption]()},
+		{`{"hosts":[]}`, "hosts", "array", reflect.TypeFor[map[string]User]()},
+	} {
+		err := jsoninline.UnmarshalOptions([]byte(tc.data), &cfg, nil)
+		if !errors.As(err, &fe) || fe.JSONPath != tc.path {
+			t.Fatalf("%s: expected FieldError at %s, got %v", tc.data, tc.path, err)
+		}
+		if !errors.As(err, &te) || te.Value != tc.value || te.Type != tc.typ {
+			t.Fatalf("%s: unexpected error %v", tc.data, err)
+		}
+		want := "jsoninline: " + tc.path + ": json: cannot unmarshal " + tc.value + " into Go value of type " + tc.typ.String()
+		if err.Error() != want {
+			t.Fatalf("unexpected message\n got: %s\nwant: %s", err, want)
+		}
+	}
+
+	// of several bad map entries, the first one in the document is
+	// reported
+	data = `{"hosts":{"z":{"id":"x"},"a":{"id":"y"},"m":{"id":"z"}}}`
+	for range 20 {
+		err = jsoninline.UnmarshalOptions([]byte(data), &cfg, nil)
+		if !errors.As(err, &fe) || fe.JSONPath != "hosts.z.id" || fe.GoPath != `Hosts["z"].ID` {
+			t.Fatalf("expected FieldError at hosts.z.id, got %v", err)
+		}
+	}
+
+	// errors located by a decode inside an UnmarshalJSON method are
+	// located again in the outer value
+	data = `{"items":[{"a":1},{"a":"x"}]}`
+	var list CustomList
+	err = jsoninline.UnmarshalOptions([]byte(data), &list, nil)
+	if !errors.As(err, &fe) {
+		t.Fatalf("expected FieldError, got %v", err)
+	}
+	if fe.JSONPath != "items[1].a" || fe.GoPath != "Items[1].A" {
+		t.Fatalf("unexpected paths %q %q", fe.JSONPath, fe.GoPath)
+	}
+	if want := int64(strings.Index(data, `"x"`)); fe.Offset != want {
+		t.Fatalf("unexpected offset %d, want %d", fe.Offset, want)
+	}
+	if !errors.As(err, &te) || errors.As(fe.Err, new(*jsoninline.FieldError)) {
+		t.Fatalf("expected the UnmarshalTypeError to be wrapped once, got %v", fe.Err)
+	}
+
+	hosts := []Host{{Name: "a", Metrics: &Metrics{Load: 1}}, {Name: "b", Metrics: &Metrics{Load: math.NaN()}}}
+	_, err = jsoninline.Marshal(map[string]any{"hosts": hosts})
+	if !errors.As(err, &fe) {
+		t.Fatalf("expected FieldError, got %v", err)
+	}
+	if fe.JSONPath != "hosts[1].load" || fe.GoPath != `["hosts"][1].Metrics.Load` || fe.Offset != -1 {
+		t.Fatalf("unexpected error %+v", fe)
+	}
+	var ue *json.UnsupportedValueError
+	if !errors.As(err, &ue) {
+		t.Fatalf("expected the UnsupportedValueError to be wrapped, got %v", fe.Err)
+	}
+}
+
+type Envelope struct {
+	Kind    string `json:"kind"`
+	Payload any    `json:"payload"`
+}
+
+// TestUnmarshalInterface ensures a pointer held by an interface is decoded
+// into with inline semantics, as encoding/json decodes into it.
+func TestUnmarshalInterface(t *testing.T) {
+	env := Envelope{Payload: &User{}}
+	data := `{"kind":"user","payload":{"id":1,"city":"z"}}`
+	if err := json.Unmarshal([]byte(data), jsoninline.V(&env)); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	u, ok := env.Payload.(*User)
+	if !ok || u.ID != 1 || u.China == nil || u.China.City != "z" {
+		t.Fatalf("unexpected payload: %#v", env.Payload)
+	}
+
+	var x any = &User{}
+	if err := jsoninline.UnmarshalOptions([]byte(`{"id":2,"state":"TX"}`), &x, nil); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if u := x.(*User); u.ID != 2 || u.USA == nil || u.USA.State != "TX" {
+		t.Fatalf("unexpected value: %+v", u)
+	}
+
+	// null clears the interface, and interfaces without a pointer get
+	// the generic value
+	if err := jsoninline.UnmarshalOptions([]byte(`null`), &x, nil); err != nil || x != nil {
+		t.Fatalf("unexpected result %v: %#v", err, x)
+	}
+	env = Envelope{Payload: User{}}
+	if err := json.Unmarshal([]byte(data), jsoninline.V(&env)); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if _, ok := env.Payload.(map[string]any); !ok {
+		t.Fatalf("unexpected payload: %#v", env.Payload)
+	}
+}
+
+type deepValue struct {
+	E *deepValue `json:"e"`
+}
+
+// deepJSON returns depth objects nested in each other under the key "e".
+func deepJSON(depth int) []byte {
+	return []byte(strings.Repeat(`{"e":`, depth) + "null" + strings.Repeat("}", depth))
+}
+
+func BenchmarkUnmarshalDeep(b *testing.B) {
+	data := deepJSON(4000)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for b.Loop() {
+		var v deepValue
+		if err := jsoninline.UnmarshalOptions(data, &v, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,770 @@
+package jsoninline
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// encodeState writes the JSON encoding of a value into a buffer in a single
+// pass over the value.
+type encodeState struct {
+	bytes.Buffer
+	opts       Options
+	escapeHTML bool
+	scratch    [64]byte
+	members    []member       // stack of pending object members, see structValue
+	inlines    []inlineFrame  // inline fields the pending members come from
+	positions  map[string]int // positions of keys in the output of resolve
+
+	// ptrLevel counts the pointers, maps and slices being encoded, and
+	// ptrSeen holds them once ptrLevel is deep enough to check for cycles.
+	ptrLevel uint
+	ptrSeen  map[seenKey]struct{}
+}
+
+// startDetectingCyclesAfter is the nesting depth after which value starts
+// checking for cycles, as in encoding/json.
+const startDetectingCyclesAfter = 1000
+
+// seenKey identifies a pointer, map or slice for cycle detection.
+type seenKey struct {
+	typ reflect.Type
+	ptr uintptr
+	len int
+}
+
+// member is an object member waiting to be written. Either f and v describe
+// a struct field, or name is a key of a dynamic member whose value is raw,
+// if already encoded, or v.
+type member struct {
+	name   string
+	f      *field
+	v      reflect.Value
+	raw    []byte
+	parent int  // index into inlines of the inline field holding the member, or -1
+	depth  int  // number of inline fields between the object and the member
+	remain bool // an entry of a remain or inline map
+}
+
+// inlineFrame records an inline field whose members are being collected.
+type inlineFrame struct {
+	f      *field
+	parent int
+}
+
+var encodeStatePool sync.Pool
+
+func newEncodeState(opts Options) *encodeState {
+	if v := encodeStatePool.Get(); v != nil {
+		e := v.(*encodeState)
+		e.Reset()
+		e.opts = opts
+		e.escapeHTML = true
+		e.ptrLevel = 0
+		clear(e.ptrSeen)
+		return e
+	}
+	return &encodeState{opts: opts, escapeHTML: true}
+}
+
+func (e *encodeState) release() {
+	clear(e.members)
+	e.members = e.members[:0]
+	clear(e.inlines)
+	e.inlines = e.inlines[:0]
+	encodeStatePool.Put(e)
+}
+
+func marshal(p any, opts Options) ([]byte, error) {
+	e := newEncodeState(opts)
+	defer e.release()
+
+	if err := e.value(reflect.ValueOf(p)); err != nil {
+		return nil, finishPath(err)
+	}
+	return bytes.Clone(e.Bytes()), nil
+}
+
+// value writes v, inlining the fields of any struct it reaches through
+// pointers, interfaces, slices, arrays and maps. Values implementing
+// json.Marshaler or encoding.TextMarshaler are left to encoding/json.
+func (e *encodeState) value(v reflect.Value) error {
+	if !v.IsValid() || (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		e.WriteString("null")
+		return nil
+	}
+	if v.Type().Implements(inlineValuerType) && v.CanInterface() {
+		x, opts := v.Interface().(inlineValuer).inlineValue()
+		return e.wrapped(x, opts)
+	}
+	if m, ok := marshalerValue(v); ok {
+		if jm, ok := m.(json.Marshaler); ok {
+			return e.marshaler(jm, v.Type())
+		}
+		return e.stdlib(m)
+	}
+
+	if k := v.Kind(); k == reflect.Ptr || k == reflect.Map || k == reflect.Slice {
+		key, err := e.enter(v)
+		if err != nil {
+			return err
+		}
+		defer e.leave(key)
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return e.value(v.Elem())
+	case reflect.Slice:
+		if v.IsNil() {
+			e.WriteString("null")
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return e.plain(v)
+		}
+		return e.array(v)
+	case reflect.Array:
+		return e.array(v)
+	case reflect.Map:
+		return e.mapValue(v)
+	case reflect.Struct:
+		return e.structValue(v)
+	}
+	return e.plain(v)
+}
+
+// enter records that the pointer, map or slice v is being encoded. Once
+// the nesting is deep enough, it reports a cycle if v is already being
+// encoded. A successful call must be matched by a call to leave with the
+// returned key.
+func (e *encodeState) enter(v reflect.Value) (seenKey, error) {
+	if e.ptrLevel++; e.ptrLevel <= startDetectingCyclesAfter {
+		return seenKey{}, nil
+	}
+	key := seenKey{typ: v.Type(), ptr: v.Pointer()}
+	if v.Kind() == reflect.Slice {
+		key.len = v.Len()
+	}
+	if _, ok := e.ptrSeen[key]; ok {
+		e.ptrLevel--
+		return seenKey{}, &json.UnsupportedValueError{Value: v, Str: "encountered a cycle via " + v.Type().String()}
+	}
+	if e.ptrSeen == nil {
+		e.ptrSeen = make(map[seenKey]struct{})
+	}
+	e.ptrSeen[key] = struct{}{}
+	return key, nil
+}
+
+func (e *encodeState) leave(key seenKey) {
+	if key.typ != nil {
+		delete(e.ptrSeen, key)
+	}
+	e.ptrLevel--
+}
+
+// wrapped writes the value x held by a wrapper, using opts if not nil.
+func (e *encodeState) wrapped(x any, opts *Options) error {
+	if opts != nil {
+		saved := e.opts
+		e.opts = *opts
+		defer func() { e.opts = saved }()
+	}
+	return e.value(reflect.ValueOf(x))
+}
+
+func (e *encodeState) array(v reflect.Value) error {
+	e.WriteByte('[')
+	for i := 0; i < v.Len(); i++ {
+		if i > 0 {
+			e.WriteByte(',')
+		}
+		if err := e.value(v.Index(i)); err != nil {
+			return prependPath(err, pathElem{index: i})
+		}
+	}
+	e.WriteByte(']')
+	return nil
+}
+
+// mapValue writes v with its keys sorted, as encoding/json does. Keys are
+// strings, integers or encoding.TextMarshaler values.
+func (e *encodeState) mapValue(v reflect.Value) error {
+	if v.IsNil() {
+		e.WriteString("null")
+		return nil
+	}
+	kt := v.Type().Key()
+	switch kt.Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+	default:
+		if !kt.Implements(textMarshalerType) {
+			return &json.UnsupportedTypeError{Type: v.Type()}
+		}
+	}
+
+	type entry struct {
+		key string
+		v   reflect.Value
+	}
+	entries := make([]entry, 0, v.Len())
+	for iter := v.MapRange(); iter.Next(); {
+		key, err := mapKey(iter.Key())
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{key, iter.Value()})
+	}
+	slices.SortFunc(entries, func(a, b entry) int {
+		return strings.Compare(a.key, b.key)
+	})
+
+	e.WriteByte('{')
+	for i, ent := range entries {
+		if i > 0 {
+			e.WriteByte(',')
+		}
+		e.Write(appendString(e.AvailableBuffer(), ent.key, e.escapeHTML))
+		e.WriteByte(':')
+		if err := e.value(ent.v); err != nil {
+			return prependPath(err, pathElem{key: ent.key, index: -1})
+		}
+	}
+	e.WriteByte('}')
+	return nil
+}
+
+// mapKey returns the object key of the map key k. Like encoding/json, it
+// prefers the string value of a string kind over its MarshalText method.
+func mapKey(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+	if tm, ok := k.Interface().(encoding.TextMarshaler); ok {
+		if k.Kind() == reflect.Pointer && k.IsNil() {
+			return "", nil
+		}
+		b, err := tm.MarshalText()
+		if err != nil {
+			return "", &json.MarshalerError{Type: k.Type(), Err: err}
+		}
+		return string(b), nil
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+	return "", &json.UnsupportedTypeError{Type: k.Type()}
+}
+
+// structValue writes v as a JSON object. Fields of inline members are
+// written at the position of the inline field. When several members share a
+// key, the key stays where it first appeared and the conflict policy picks
+// its value.
+func (e *encodeState) structValue(v reflect.Value) error {
+	fs, err := cachedTypeFields(v.Type(), e.opts.tags())
+	if err != nil {
+		return err
+	}
+
+	start, inlines := len(e.members), len(e.inlines)
+	defer func() {
+		clear(e.members[start:])
+		e.members = e.members[:start]
+		clear(e.inlines[inlines:])
+		e.inlines = e.inlines[:inlines]
+	}()
+	if err := e.collect(v, fs, -1, 0); err != nil {
+		return err
+	}
+	if fs.mayConflict {
+		ms, err := e.resolve(v.Type(), e.members[start:])
+		if err != nil {
+			return err
+		}
+		n := start + len(ms)
+		clear(e.members[n:])
+		e.members = e.members[:n]
+	}
+
+	e.WriteByte('{')
+	for i := start; i < len(e.members); i++ {
+		if i > start {
+			e.WriteByte(',')
+		}
+		m := e.members[i]
+		if m.f != nil {
+			if e.escapeHTML {
+				e.Write(m.f.nameBytes)
+			} else {
+				e.Write(m.f.nameNonEsc)
+			}
+		} else {
+			e.Write(appendString(e.scratch[:0], m.name, e.escapeHTML))
+			e.WriteByte(':')
+			if m.raw != nil {
+				e.Write(m.raw)
+				continue
+			}
+		}
+		if m.f != nil && m.f.quoted {
+			err = e.quoted(m.v)
+		} else {
+			err = e.value(m.v)
+		}
+		if err != nil {
+			return prependPath(err, pathElem{key: m.name, index: -1, goPath: e.path(m)})
+		}
+	}
+	e.WriteByte('}')
+	return nil
+}
+
+// collect appends the members of the struct v to e.members, descending into
+// inline fields. parent and depth locate v among the inline fields of the
+// object being written.
+func (e *encodeState) collect(v reflect.Value, fs *structFields, parent, depth int) error {
+	var selected string
+	if fs.discriminator >= 0 {
+		disc := &fs.list[fs.discriminator]
+		if dv, ok := fieldByIndex(v, disc.index); ok {
+			selected = discriminatorValue(dv, disc, e.opts)
+		}
+		if e.opts.RequireVariant {
+			if err := fs.checkVariant(v.Type(), selected); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i := range fs.list {
+		f := &fs.list[i]
+		// only the variant chosen by the discriminator is emitted
+		if f.variant != "" && f.variant != selected {
+			continue
+		}
+
+		fv, ok := fieldByIndex(v, f.index)
+		if !ok {
+			continue
+		}
+		if f.inline {
+			e.inlines = append(e.inlines, inlineFrame{f: f, parent: parent})
+			if err := e.inline(fv, len(e.inlines)-1, depth+1); err != nil {
+				return err
+			}
+			continue
+		}
+		if f.remain {
+			e.inlines = append(e.inlines, inlineFrame{f: f, parent: parent})
+			e.remain(fv, len(e.inlines)-1, depth+1)
+			continue
+		}
+
+		if f.omit(fv, &e.opts) {
+			continue
+		}
+		e.members = append(e.members, member{name: f.name, f: f, v: fv, parent: parent, depth: depth})
+	}
+	return nil
+}
+
+// inline appends the members of the inline field value v to e.members.
+// Structs contribute their fields; any other value, including structs with
+// their own MarshalJSON or MarshalText, must encode to a JSON object whose
+// members are spliced in as raw values.
+func (e *encodeState) inline(v reflect.Value, parent, depth int) error {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		if v.Kind() == reflect.Ptr {
+			key, err := e.enter(v)
+			if err != nil {
+				return err
+			}
+			defer e.leave(key)
+		}
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Struct && !hasCodec(v.Type()) {
+		fs, err := cachedTypeFields(v.Type(), e.opts.tags())
+		if err != nil {
+			return err
+		}
+		return e.collect(v, fs, parent, depth)
+	}
+
+	ie := newEncodeState(e.opts)
+	ie.escapeHTML = e.escapeHTML
+	defer ie.release()
+	// continue the cycle detection of e; every entry ie adds is removed
+	// by the time it returns
+	ie.ptrLevel, ie.ptrSeen = e.ptrLevel, e.ptrSeen
+	err := ie.value(v)
+	ie.ptrSeen = nil // not to be cleared when ie is reused
+	if err != nil {
+		return err
+	}
+	b := ie.Bytes()
+	obj, err := parseObject(b)
+	if err != nil {
+		return err
+	}
+	for _, k := range obj.keys {
+		e.members = append(e.members, member{name: k, raw: obj.values[k], parent: parent, depth: depth})
+	}
+	return nil
+}
+
+// remain appends the entries of the remain map v to e.members in key
+// order.
+func (e *encodeState) remain(v reflect.Value, parent, depth int) {
+	keys := v.MapKeys()
+	slices.SortFunc(keys, func(a, b reflect.Value) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	for _, k := range keys {
+		e.members = append(e.members, member{name: k.String(), v: v.MapIndex(k), parent: parent, depth: depth, remain: true})
+	}
+}
+
+// resolve removes members sharing a key according to the conflict policy.
+// The surviving member takes the position of the first one. Remain map
+// entries only hold keys no field produces, so unless the policy is
+// ErrorOnConflict a field always wins over them.
+func (e *encodeState) resolve(t reflect.Type, ms []member) ([]member, error) {
+	if e.positions == nil {
+		e.positions = make(map[string]int, len(ms))
+	}
+	defer func() {
+		// clearing a map costs its capacity, so drop large ones
+		if len(e.positions) > 1024 {
+			e.positions = nil
+		}
+		clear(e.positions)
+	}()
+	out := ms[:0]
+	for _, m := range ms {
+		j, ok := e.positions[m.name]
+		if !ok {
+			e.positions[m.name] = len(out)
+			out = append(out, m)
+			continue
+		}
+		switch policy := e.opts.ConflictPolicy; {
+		case policy == ErrorOnConflict:
+			return nil, &ConflictError{Type: t, Key: m.name, Fields: [2]string{e.path(out[j]), e.path(m)}}
+		case m.remain != out[j].remain:
+			// a field always wins over a remain entry
+			if !m.remain {
+				out[j] = m
+			}
+		case policy == FirstWins:
+		case policy == ParentWins:
+			if m.depth <= out[j].depth {
+				out[j] = m
+			}
+		default:
+			out[j] = m
+		}
+	}
+	return out, nil
+}
+
+// path returns the Go path of m relative to the object being written.
+func (e *encodeState) path(m member) string {
+	var name string
+	if m.f != nil {
+		name = m.f.goPath
+	} else {
+		name = "[" + strconv.Quote(m.name) + "]"
+	}
+	for i := m.parent; i >= 0; i = e.inlines[i].parent {
+		if m.f == nil && i == m.parent {
+			name = e.inlines[i].f.goPath + name
+			continue
+		}
+		name = e.inlines[i].f.goPath + "." + name
+	}
+	return name
+}
+
+var (
+	marshalerType     = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// marshalerValue returns the value whose MarshalJSON or MarshalText method
+// encoding/json would call for v, if any.
+func marshalerValue(v reflect.Value) (any, bool) {
+	if !v.CanInterface() {
+		return nil, false
+	}
+	t := v.Type()
+	if t.Implements(marshalerType) || t.Implements(textMarshalerType) {
+		return v.Interface(), true
+	}
+	if v.CanAddr() {
+		pt := reflect.PointerTo(t)
+		if pt.Implements(marshalerType) || pt.Implements(textMarshalerType) {
+			return v.Addr().Interface(), true
+		}
+	}
+	return nil, false
+}
+
+// quoted writes the scalar v of a field with the string option: its JSON
+// encoding inside a JSON string. Values with their own codec ignore the
+// option, as in encoding/json.
+func (e *encodeState) quoted(v reflect.Value) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			e.WriteString("null")
+			return nil
+		}
+		v = v.Elem()
+	}
+	if _, ok := marshalerValue(v); ok {
+		return e.value(v)
+	}
+	if v.Kind() == reflect.String && v.Type() != numberType {
+		b := appendString(e.scratch[:0], v.String(), e.escapeHTML)
+		e.Write(appendString(e.AvailableBuffer(), string(b), e.escapeHTML))
+		return nil
+	}
+	e.WriteByte('"')
+	if err := e.plain(v); err != nil {
+		return err
+	}
+	e.WriteByte('"')
+	return nil
+}
+
+// plain writes v without inline handling. Basic kinds are written directly;
+// everything else is delegated to encoding/json.
+func (e *encodeState) plain(v reflect.Value) error {
+	if v.Type() == numberType {
+		// json.Number is written as the number it holds
+		s := v.String()
+		if s == "" {
+			s = "0"
+		}
+		if !isValidNumber(s) {
+			return errors.New("json: invalid number literal " + strconv.Quote(s))
+		}
+		e.WriteString(s)
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		e.Write(strconv.AppendBool(e.scratch[:0], v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.Write(strconv.AppendInt(e.scratch[:0], v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.Write(strconv.AppendUint(e.scratch[:0], v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		b, err := appendFloat(e.scratch[:0], v)
+		if err != nil {
+			return err
+		}
+		e.Write(b)
+	case reflect.String:
+		e.Write(appendString(e.AvailableBuffer(), v.String(), e.escapeHTML))
+	default:
+		return e.stdlib(v.Interface())
+	}
+	return nil
+}
+
+// marshaler writes the output of m.MarshalJSON exactly as returned, after
+// checking that it is valid JSON. Unlike encoding/json it does not compact
+// it or escape HTML in it, so that raw fragments such as a json.RawMessage
+// are kept byte for byte.
+func (e *encodeState) marshaler(m json.Marshaler, t reflect.Type) error {
+	b, err := m.MarshalJSON()
+	if err == nil && !json.Valid(b) {
+		// compact to get a syntax error locating the problem
+		err = json.Compact(new(bytes.Buffer), b)
+	}
+	if err != nil {
+		return &json.MarshalerError{Type: t, Err: err}
+	}
+	e.Write(b)
+	return nil
+}
+
+func (e *encodeState) stdlib(v any) error {
+	if !e.escapeHTML {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+		e.Write(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	e.Write(b)
+	return nil
+}
+
+var numberType = reflect.TypeFor[json.Number]()
+
+// isValidNumber reports whether s is a valid JSON number literal.
+func isValidNumber(s string) bool {
+	if s == "" {
+		return false
+	}
+	if s[0] == '-' {
+		s = s[1:]
+		if s == "" {
+			return false
+		}
+	}
+
+	// Digits
+	switch {
+	default:
+		return false
+	case s[0] == '0':
+		s = s[1:]
+	case '1' <= s[0] && s[0] <= '9':
+		s = s[1:]
+		for len(s) > 0 && '0' <= s[0] && s[0] <= '9' {
+			s = s[1:]
+		}
+	}
+
+	// . followed by 1 or more digits.
+	if len(s) >= 2 && s[0] == '.' && '0' <= s[1] && s[1] <= '9' {
+		s = s[2:]
+		for len(s) > 0 && '0' <= s[0] && s[0] <= '9' {
+			s = s[1:]
+		}
+	}
+
+	// e or E followed by an optional - or + and
+	// 1 or more digits.
+	if len(s) >= 2 && (s[0] == 'e' || s[0] == 'E') {
+		s = s[1:]
+		if s[0] == '+' || s[0] == '-' {
+			s = s[1:]
+			if s == "" {
+				return false
+			}
+		}
+		for len(s) > 0 && '0' <= s[0] && s[0] <= '9' {
+			s = s[1:]
+		}
+	}
+
+	// Make sure we are at the end.
+	return s == ""
+}
+
+// appendFloat formats a float the same way encoding/json does.
+func appendFloat(b []byte, v reflect.Value) ([]byte, error) {
+	bits := v.Type().Bits()
+	f := v.Float()
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, &json.UnsupportedValueError{Value: v, Str: strconv.FormatFloat(f, 'g', -1, bits)}
+	}
+
+	// Convert as if by ES6 number to string conversion.
+	abs := math.Abs(f)
+	fmt := byte('f')
+	if abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			fmt = 'e'
+		}
+	}
+	b = strconv.AppendFloat(b, f, fmt, -1, bits)
+	if fmt == 'e' {
+		// clean up e-09 to e-9
+		n := len(b)
+		if n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	return b, nil
+}
+
+const hex = "0123456789abcdef"
+
+// appendString appends s as a quoted JSON string, escaping it the same way
+// encoding/json does.
+func appendString(dst []byte, s string, escapeHTML bool) []byte {
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= 0x20 && b != '"' && b != '\\' && (!escapeHTML || b != '<' && b != '>' && b != '&') {
+				i++
+				continue
+			}
+			dst = append(dst, s[start:i]...)
+			switch b {
+			case '\\', '"':
+				dst = append(dst, '\\', b)
+			case '\b':
+				dst = append(dst, '\\', 'b')
+			case '\f':
+				dst = append(dst, '\\', 'f')
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			case '\t':
+				dst = append(dst, '\\', 't')
+			default:
+				// This encodes bytes < 0x20 except for \b, \f, \n, \r and \t,
+				// and <, > and & when escaping HTML.
+				dst = append(dst, '\\', 'u', '0', '0', hex[b>>4], hex[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		c, size := utf8.DecodeRuneInString(s[i:])
+		if c == utf8.RuneError && size == 1 {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		// U+2028 is LINE SEPARATOR and U+2029 is PARAGRAPH SEPARATOR. They
+		// are valid JSON but break JavaScript, so they are escaped too.
+		if c == '\u2028' || c == '\u2029' {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, '\\', 'u', '2', '0', '2', hex[c&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	dst = append(dst, s[start:]...)
+	dst = append(dst, '"')
+	return dst
+}
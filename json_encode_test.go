@@ -0,0 +1,427 @@
+package jsoninline_test
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/hydrz/jsoninline"
+)
+
+type plainKinds struct {
+	String  string         `json:"string"`
+	HTML    string         `json:"html"`
+	Int     int64          `json:"int"`
+	Uint    uint8          `json:"uint"`
+	Float32 float32        `json:"float32"`
+	Float64 float64        `json:"float64"`
+	Small   float64        `json:"small"`
+	Bool    bool           `json:"bool"`
+	Bytes   []byte         `json:"bytes"`
+	Map     map[string]int `json:"map"`
+	Time    time.Time      `json:"time"`
+	Ptr     *string        `json:"ptr"`
+	Any     any            `json:"any"`
+}
+
+// TestMarshalMatchesEncodingJSON ensures values of a struct without inline
+// fields are written exactly as encoding/json writes them.
+func TestMarshalMatchesEncodingJSON(t *testing.T) {
+	v := plainKinds{
+		String:  "line\nbreak \"quoted\" \u2028 \x01",
+		HTML:    "<a href=\"x\">&</a>",
+		Int:     -42,
+		Uint:    255,
+		Float32: 3.14,
+		Float64: 1e21,
+		Small:   0.000001234,
+		Bool:    true,
+		Bytes:   []byte("hello"),
+		Map:     map[string]int{"b": 2, "a": 1},
+		Time:    time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Any:     []any{1, "two", nil},
+	}
+
+	got, err := json.Marshal(jsoninline.V(v))
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	want, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("encoding/json marshal failed: %v", err)
+	}
+	if string(got) != string(want) {
+		t.Fatalf("output differs from encoding/json\n got: %s\nwant: %s", got, want)
+	}
+}
+
+func benchmarkOptions(n int) []DNSServerOption {
+	opts := make([]DNSServerOption, n)
+	for i := range opts {
+		switch i % 3 {
+		case 0:
+			opts[i] = DNSServerOption{Type: "local", Tag: "local-dns", Local: LocalDNSServerOption{PreferGO: true}}
+		case 1:
+			opts[i] = DNSServerOption{Type: "udp", Tag: "udp-dns", UDP: &UDPDNSServerOption{Server: "1.1.1.1", ServerPort: 53}}
+		default:
+			opts[i] = DNSServerOption{Type: "tls", Tag: "tls-dns", TLS: &TLSDNSServerOption{Server: "dns.google", ServerPort: 853, SNI: "dns.google"}}
+		}
+	}
+	return opts
+}
+
+// roundTripMarshal mirrors the previous encoder, which passed every field
+// and element through json.Marshal, json.Unmarshal and json.Marshal again.
+// It is kept as a baseline for the benchmarks below.
+func roundTripMarshal(p any) ([]byte, error) {
+	v := reflect.ValueOf(p)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Slice {
+		out := make([]any, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			b, err := roundTripMarshal(v.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			var val any
+			if err := json.Unmarshal(b, &val); err != nil {
+				return nil, err
+			}
+			out = append(out, val)
+		}
+		return json.Marshal(out)
+	}
+	if v.Kind() != reflect.Struct {
+		return json.Marshal(p)
+	}
+
+	m := make(map[string]any)
+	for i := 0; i < v.NumField(); i++ {
+		sf := v.Type().Field(i)
+		name, opts, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		fv := v.Field(i)
+		if opts == "inline" {
+			if fv.Kind() == reflect.Ptr && fv.IsNil() {
+				continue
+			}
+			b, err := roundTripMarshal(fv.Interface())
+			if err != nil {
+				return nil, err
+			}
+			var inline map[string]any
+			if err := json.Unmarshal(b, &inline); err != nil {
+				return nil, err
+			}
+			for k, val := range inline {
+				m[k] = val
+			}
+			continue
+		}
+		b, err := json.Marshal(fv.Interface())
+		if err != nil {
+			return nil, err
+		}
+		var val any
+		if err := json.Unmarshal(b, &val); err != nil {
+			return nil, err
+		}
+		m[name] = val
+	}
+	return json.Marshal(m)
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	opts := benchmarkOptions(1000)
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := json.Marshal(jsoninline.V(opts)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMarshalRoundTrip(b *testing.B) {
+	opts := benchmarkOptions(1000)
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := roundTripMarshal(opts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+type zeroer struct {
+	N int
+}
+
+func (z zeroer) IsZero() bool { return z.N <= 0 }
+
+type omitKinds struct {
+	String   string            `json:"string,omitempty"`
+	Int      int               `json:"int,omitempty"`
+	Float    float64           `json:"float,omitempty"`
+	Bool     bool              `json:"bool,omitempty"`
+	Slice    []int             `json:"slice,omitempty"`
+	Map      map[string]string `json:"map,omitempty"`
+	Ptr      *int              `json:"ptr,omitempty"`
+	Any      any               `json:"any,omitempty"`
+	Struct   struct{}          `json:"struct,omitempty"`
+	Time     time.Time         `json:"time,omitzero"`
+	Array    [2]int            `json:"array,omitzero"`
+	Zeroer   zeroer            `json:"zeroer,omitzero"`
+	ZeroerP  *zeroer           `json:"zeroer_p,omitzero"`
+	NonEmpty []int             `json:"non_empty,omitzero"`
+}
+
+// TestMarshalOmitParity ensures omitempty and omitzero drop exactly the
+// fields encoding/json drops.
+func TestMarshalOmitParity(t *testing.T) {
+	tests := []omitKinds{
+		{},
+		{Zeroer: zeroer{N: -1}, ZeroerP: &zeroer{}, NonEmpty: []int{}},
+		{String: "s", Int: 1, Float: 0.5, Bool: true, Slice: []int{1}, Map: map[string]string{"k": "v"},
+			Ptr: new(int), Any: 0, Time: time.Unix(1, 0).UTC(), Array: [2]int{0, 1}, Zeroer: zeroer{N: 1}},
+	}
+	for _, v := range tests {
+		got, err := json.Marshal(jsoninline.V(v))
+		if err != nil {
+			t.Fatalf("marshal failed: %v", err)
+		}
+		want, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("encoding/json marshal failed: %v", err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("output differs from encoding/json\n got: %s\nwant: %s", got, want)
+		}
+	}
+}
+
+type quotedKinds struct {
+	Port   int         `json:"port,string"`
+	Ratio  float64     `json:"ratio,string"`
+	On     bool        `json:"on,string"`
+	Name   string      `json:"name,string"`
+	Ptr    *uint16     `json:"ptr,string"`
+	Nil    *int        `json:"nil,string"`
+	Time   time.Time   `json:"time,string"`
+	Slice  []int       `json:"slice,string"`
+	Inline *quotedPort `json:",inline"`
+}
+
+type quotedPort struct {
+	ServerPort int `json:"server_port,string"`
+}
+
+// TestStringOption ensures the string option quotes scalars like
+// encoding/json does, including the fields of inline structs, and that
+// quoted values decode back.
+func TestStringOption(t *testing.T) {
+	port := uint16(8080)
+	v := quotedKinds{
+		Port: 53, Ratio: 0.5, On: true, Name: `a "b" <c>`, Ptr: &port,
+		Time: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Slice: []int{1},
+		Inline: &quotedPort{ServerPort: 853},
+	}
+
+	got, err := jsoninline.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	// encoding/json writes the inline struct nested; compare the rest
+	plain := v
+	plain.Inline = nil
+	want, err := json.Marshal(plain)
+	if err != nil {
+		t.Fatalf("encoding/json marshal failed: %v", err)
+	}
+	want = append(want[:len(want)-len(`,"Inline":null}`)], `,"server_port":"853"}`...)
+	if string(got) != string(want) {
+		t.Fatalf("output differs from encoding/json\n got: %s\nwant: %s", got, want)
+	}
+
+	decoded, err := jsoninline.Unmarshal[quotedKinds](got)
+	if err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, v) {
+		t.Fatalf("round trip mismatch\n got: %+v\nwant: %+v", decoded, v)
+	}
+
+	for _, data := range []string{`{"port":53}`, `{"name":"plain"}`, `{"server_port":"x"}`} {
+		if _, err := jsoninline.Unmarshal[quotedKinds]([]byte(data)); err == nil {
+			t.Errorf("expected an error for %s", data)
+		}
+	}
+
+	// the schema describes quoted values as strings
+	schema, err := jsoninline.For[quotedKinds](&jsonschema.ForOptions{})
+	if err != nil {
+		t.Fatalf("failed to generate schema: %v", err)
+	}
+	resolved, err := schema.Resolve(&jsonschema.ResolveOptions{})
+	if err != nil {
+		t.Fatalf("failed to resolve schema: %v", err)
+	}
+	var doc any
+	if err := json.Unmarshal(got, &doc); err != nil {
+		t.Fatalf("failed to parse output: %v", err)
+	}
+	if err := resolved.Validate(doc); err != nil {
+		t.Fatalf("schema rejects the output %s: %v", got, err)
+	}
+	if err := resolved.Validate(map[string]any{"port": 53}); err == nil {
+		t.Fatalf("schema accepts an unquoted port")
+	}
+}
+
+type Sizes struct {
+	ID    uint64        `json:"id"`
+	Delta int64         `json:"delta"`
+	TTL   time.Duration `json:"ttl"`
+	Limit json.Number   `json:"limit"`
+	Count json.Number   `json:"count,string"`
+	Meta  *SizeMeta     `json:",inline"`
+}
+
+type SizeMeta struct {
+	Extra any `json:"extra"`
+}
+
+// TestLosslessNumbers ensures large integers and json.Number values are
+// written exactly, and that UseNumber keeps the digits of numbers decoded
+// into interface values.
+func TestLosslessNumbers(t *testing.T) {
+	v := Sizes{
+		ID:    math.MaxUint64,
+		Delta: math.MinInt64 + 1,
+		TTL:   9007199254740993,
+		Limit: "12345678901234567890.5e3",
+		Count: "9007199254740993",
+		Meta:  &SizeMeta{Extra: []any{uint64(1<<63 + 1), json.Number("1e400")}},
+	}
+	got, err := jsoninline.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	want := `{"id":18446744073709551615,"delta":-9223372036854775807,"ttl":9007199254740993,` +
+		`"limit":12345678901234567890.5e3,"count":"9007199254740993","extra":[9223372036854775809,1e400]}`
+	if string(got) != want {
+		t.Fatalf("unexpected output\n got: %s\nwant: %s", got, want)
+	}
+
+	if _, err := jsoninline.Marshal(Sizes{Limit: "0x10"}); err == nil {
+		t.Fatal("expected an error for an invalid json.Number")
+	}
+
+	var decoded Sizes
+	if err := jsoninline.UnmarshalOptions(got, &decoded, &jsoninline.Options{UseNumber: true}); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	extra, _ := decoded.Meta.Extra.([]any)
+	if decoded.ID != v.ID || decoded.TTL != v.TTL || decoded.Count != v.Count || len(extra) != 2 ||
+		extra[0] != json.Number("9223372036854775809") || extra[1] != json.Number("1e400") {
+		t.Fatalf("unexpected value: %+v %v", decoded, decoded.Meta.Extra)
+	}
+}
+
+type signedBlob struct{}
+
+func (signedBlob) MarshalJSON() ([]byte, error) {
+	return []byte(`{"z": 1, "a": [1.50, 1e2], "html": "<b>"}`), nil
+}
+
+type brokenBlob struct{}
+
+func (brokenBlob) MarshalJSON() ([]byte, error) {
+	return []byte(`{"a":}`), nil
+}
+
+type Plugin struct {
+	Name    string          `json:"name"`
+	Payload json.RawMessage `json:"payload"`
+	Opts    *PluginOpts     `json:",inline"`
+}
+
+type PluginOpts struct {
+	Signed signedBlob `json:"signed"`
+}
+
+// TestRawPassthrough ensures raw JSON and MarshalJSON output are written
+// byte for byte, and that invalid output is rejected.
+func TestRawPassthrough(t *testing.T) {
+	p := Plugin{Name: "p", Payload: json.RawMessage(`{ "b": 1.0, "a": 2 }`), Opts: &PluginOpts{}}
+	got, err := jsoninline.Marshal(p)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	want := `{"name":"p","payload":{ "b": 1.0, "a": 2 },"signed":{"z": 1, "a": [1.50, 1e2], "html": "<b>"}}`
+	if string(got) != want {
+		t.Fatalf("unexpected output\n got: %s\nwant: %s", got, want)
+	}
+
+	_, err = jsoninline.Marshal(map[string]any{"blob": brokenBlob{}})
+	var me *json.MarshalerError
+	if !errors.As(err, &me) {
+		t.Fatalf("expected MarshalerError, got %v", err)
+	}
+}
+
+type Node struct {
+	Name     string           `json:"name"`
+	Next     *Node            `json:"next,omitempty"`
+	Children map[string]*Node `json:"children,omitempty"`
+}
+
+type Looped struct {
+	Name string
+	X    any `json:",inline"`
+}
+
+// TestMarshalCycle ensures a value referencing itself is reported as an
+// error, as encoding/json does, instead of overflowing the stack.
+func TestMarshalCycle(t *testing.T) {
+	n := &Node{Name: "loop"}
+	n.Next = n
+	m := &Node{Name: "tree"}
+	m.Children = map[string]*Node{"self": m}
+
+	for _, v := range []*Node{n, m} {
+		_, err := jsoninline.Marshal(v)
+		var ue *json.UnsupportedValueError
+		if !errors.As(err, &ue) || !strings.Contains(err.Error(), "encountered a cycle") {
+			t.Fatalf("expected cycle error, got %v", err)
+		}
+	}
+
+	// Cycles through inline fields, including inline values encoded on
+	// their own.
+	p := &Looped{Name: "p"}
+	p.X = p
+	q := &Looped{Name: "q"}
+	q.X = map[int]any{1: q}
+	for _, v := range []*Looped{p, q} {
+		_, err := jsoninline.Marshal(v)
+		var ue *json.UnsupportedValueError
+		if !errors.As(err, &ue) || !strings.Contains(err.Error(), "encountered a cycle") {
+			t.Fatalf("expected cycle error, got %v", err)
+		}
+	}
+
+	// Deep values without a cycle still encode.
+	deep := &Node{Name: "0"}
+	for i, p := 0, deep; i < 1500; i++ {
+		p.Next = &Node{Name: "n"}
+		p = p.Next
+	}
+	if _, err := jsoninline.Marshal(deep); err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+}
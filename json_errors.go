@@ -0,0 +1,176 @@
+package jsoninline
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// UnknownFieldsError lists the JSON keys that no field consumed, reported
+// when Options.DisallowUnknownFields is set. Keys are named by their JSON
+// path, e.g. "servers[3].sever", in sorted order.
+type UnknownFieldsError struct {
+	Paths []string
+}
+
+func (e *UnknownFieldsError) Error() string {
+	return "jsoninline: unknown fields " + strings.Join(e.Paths, ", ")
+}
+
+// FieldError locates an error that occurred while encoding or decoding a
+// value nested in the top-level one, which may live in an inline struct.
+type FieldError struct {
+	// JSONPath is the path of the value in the JSON document, e.g.
+	// "servers[3].server_port".
+	JSONPath string
+
+	// GoPath is the path of the value in the Go value, e.g.
+	// "Servers[3].UDP.ServerPort", where UDP is an inline field.
+	GoPath string
+
+	// Offset is the byte offset of the value in the input when decoding,
+	// relative to the start of the value being decoded. It is -1 when
+	// encoding.
+	Offset int64
+
+	Err error
+
+	path   []pathElem // set while encoding, reversed, see prependPath
+	origin *decodeID  // the decode that located the error, if decoding
+}
+
+// decodeID identifies a decode in the errors it locates. It is not empty
+// so that pointers to distinct values compare unequal.
+type decodeID struct {
+	_ byte
+}
+
+func (e *FieldError) Error() string {
+	return "jsoninline: " + e.JSONPath + ": " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// pathElem is a step from a JSON value to a nested one: an object member
+// or, when key is empty and index is not negative, an array element.
+type pathElem struct {
+	key   string
+	index int
+
+	// goPath is the Go selector of a struct field, possibly through
+	// inline fields, or empty for map entries and slice elements.
+	goPath string
+}
+
+// fieldError returns err located at path, offset bytes into the input of
+// the decode identified by origin. A FieldError of another decode, such as
+// one returned by an UnmarshalJSON method, is located below path instead.
+func fieldError(err error, path []pathElem, offset int64, origin *decodeID) *FieldError {
+	fe := &FieldError{
+		JSONPath: formatJSONPath(path),
+		GoPath:   formatGoPath(path),
+		Offset:   offset,
+		Err:      err,
+		origin:   origin,
+	}
+	if inner, ok := err.(*FieldError); ok && inner.Offset >= 0 {
+		fe.JSONPath = joinPath(fe.JSONPath, inner.JSONPath)
+		fe.GoPath = joinPath(fe.GoPath, inner.GoPath)
+		fe.Offset += inner.Offset
+		fe.Err = inner.Err
+	}
+	return fe
+}
+
+// joinPath appends the formatted path inner to the formatted path outer.
+func joinPath(outer, inner string) string {
+	if outer == "" || inner == "" || inner[0] == '[' {
+		return outer + inner
+	}
+	return outer + "." + inner
+}
+
+// prependPath locates err one step deeper in the value being encoded,
+// after elem. The paths of the error are set by finishPath once encoding
+// has unwound.
+func prependPath(err error, elem pathElem) error {
+	fe, ok := err.(*FieldError)
+	if !ok {
+		fe = &FieldError{Offset: -1, Err: err}
+	}
+	fe.path = append(fe.path, elem)
+	return fe
+}
+
+// finishPath sets the paths of an error built by prependPath.
+func finishPath(err error) error {
+	if fe, ok := err.(*FieldError); ok && len(fe.path) > 0 {
+		slices.Reverse(fe.path)
+		fe.JSONPath, fe.GoPath = formatJSONPath(fe.path), formatGoPath(fe.path)
+		fe.path = nil
+	}
+	return err
+}
+
+// formatJSONPath formats path, e.g. "servers[3].server_port".
+func formatJSONPath(path []pathElem) string {
+	var b []byte
+	for _, p := range path {
+		switch {
+		case p.key == "" && p.index >= 0:
+			b = append(b, '[')
+			b = strconv.AppendInt(b, int64(p.index), 10)
+			b = append(b, ']')
+		case isIdentifier(p.key):
+			if len(b) > 0 {
+				b = append(b, '.')
+			}
+			b = append(b, p.key...)
+		default:
+			b = append(b, '[')
+			b = strconv.AppendQuote(b, p.key)
+			b = append(b, ']')
+		}
+	}
+	return string(b)
+}
+
+// formatGoPath formats the Go side of path, e.g.
+// "Servers[3].UDP.ServerPort".
+func formatGoPath(path []pathElem) string {
+	var b []byte
+	for _, p := range path {
+		switch {
+		case p.key == "" && p.index >= 0:
+			b = append(b, '[')
+			b = strconv.AppendInt(b, int64(p.index), 10)
+			b = append(b, ']')
+		case p.goPath != "":
+			if len(b) > 0 {
+				b = append(b, '.')
+			}
+			b = append(b, p.goPath...)
+		default:
+			b = append(b, '[')
+			b = strconv.AppendQuote(b, p.key)
+			b = append(b, ']')
+		}
+	}
+	return string(b)
+}
+
+// isIdentifier reports whether key can be written after a dot in a JSON
+// path.
+func isIdentifier(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i, c := range key {
+		if c != '_' && c != '$' && !('a' <= c && c <= 'z') && !('A' <= c && c <= 'Z') && (i == 0 || !('0' <= c && c <= '9')) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,563 @@
+package jsoninline
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// field describes how a single struct field maps onto a JSON object. The
+// same description drives encoding, decoding and schema generation.
+type field struct {
+	name       string // JSON object key
+	nameBytes  []byte // `"name":`, pre-encoded
+	nameNonEsc []byte // nameBytes without HTML escaping
+	goPath     string // Go selector path, e.g. "UDP.ServerOptions.Server"
+	index      []int  // index sequence through embedded structs
+	tagged     bool   // name comes from the struct tag
+	typ        reflect.Type
+
+	omitEmpty bool
+	omitZero  bool
+	quoted    bool                     // the string option, for a field of a kind it applies to
+	isZero    func(reflect.Value) bool // IsZero method used by omitzero, if any
+	inline    bool
+	remain    bool   // map collecting the keys no other field consumes, tagged remain or inline
+	variant   string // non-empty for inline fields selected by a discriminator
+
+	discriminator bool
+
+	// inlineType is the struct type whose fields an inline field
+	// contributes, with pointers removed. It is nil for inline fields of
+	// other kinds and for types with their own JSON codec, whose members
+	// are only known at runtime.
+	inlineType reflect.Type
+}
+
+// structFields is the cached plan of a struct type.
+type structFields struct {
+	list          []field
+	discriminator int // index into list, or -1
+
+	// keys maps every key the struct can produce, including those of
+	// nested inline structs, to the fields producing it.
+	keys map[string][]keySource
+
+	// mayConflict reports whether two members of an encoded object can
+	// share a key, either because two fields that can be present together
+	// produce it or because some inline field is only known at runtime.
+	mayConflict bool
+
+	// remain reports whether the struct or one of its inline structs has a
+	// remain field.
+	remain bool
+
+	// folded maps the case-folded form of every key to a key of keys
+	// having it, the first in field order when there are several, as in
+	// encoding/json.
+	folded map[string]string
+}
+
+// keySource is a field producing a key, possibly through inline fields.
+type keySource struct {
+	path     string // Go field path from the planned struct
+	typ      reflect.Type
+	variants []variantChoice // variants that must be selected for the field to be present
+}
+
+// variantChoice requires the discriminator of the inline struct at owner,
+// a Go path prefix, to select value.
+type variantChoice struct {
+	owner string
+	value string
+}
+
+// exclusive reports whether a and b belong to different variants of the
+// same discriminator, so that they are never present together.
+func (a keySource) exclusive(b keySource) bool {
+	for _, ca := range a.variants {
+		for _, cb := range b.variants {
+			if ca.owner == cb.owner && ca.value != cb.value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// conflict returns the first two sources that can be present together.
+func conflict(sources []keySource) (a, b keySource, ok bool) {
+	for i := range sources {
+		for j := i + 1; j < len(sources); j++ {
+			if !sources[i].exclusive(sources[j]) {
+				return sources[i], sources[j], true
+			}
+		}
+	}
+	return keySource{}, keySource{}, false
+}
+
+var fieldCache sync.Map // map[fieldsKey]*structFields
+
+// tagNames names the struct tags a plan is read from.
+type tagNames struct {
+	name      string // field names and options, in the format of the json tag
+	directive string // inline directives, see parseInlineTag
+}
+
+var defaultTags = tagNames{name: "json", directive: "jsoninline"}
+
+// fieldsKey identifies a plan: the same struct type is planned separately
+// for every set of tags it may be read from.
+type fieldsKey struct {
+	typ  reflect.Type
+	tags tagNames
+}
+
+// cachedTypeFields returns the plan of the struct type t read from tags,
+// computing it on first use.
+func cachedTypeFields(t reflect.Type, tags tagNames) (*structFields, error) {
+	key := fieldsKey{t, tags}
+	if f, ok := fieldCache.Load(key); ok {
+		return f.(*structFields), nil
+	}
+	fs, err := typeFields(t, tags)
+	if err != nil {
+		return nil, err
+	}
+	f, _ := fieldCache.LoadOrStore(key, fs)
+	return f.(*structFields), nil
+}
+
+func typeFields(t reflect.Type, tags tagNames) (*structFields, error) {
+	fs, err := fieldList(t, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	fs.keys = make(map[string][]keySource)
+	fs.folded = make(map[string]string)
+	dynamic, remain, err := expandKeys(fs, tags, "", nil, map[reflect.Type]bool{t: true}, fs.keys, fs.folded)
+	if err != nil {
+		return nil, err
+	}
+	fs.mayConflict = dynamic || remain
+	fs.remain = remain
+	for _, sources := range fs.keys {
+		if _, _, ok := conflict(sources); ok {
+			fs.mayConflict = true
+		}
+	}
+	return fs, nil
+}
+
+// fieldList computes the fields of t without looking into inline fields.
+// Fields of embedded structs are promoted following the rules of
+// encoding/json: a shallower field hides deeper ones of the same name, a
+// tagged field hides untagged ones at the same depth, and otherwise
+// fields sharing a name cancel each other out.
+func fieldList(t reflect.Type, tags tagNames) (*structFields, error) {
+	type embedded struct {
+		typ    reflect.Type
+		index  []int
+		goPath string
+	}
+
+	var fields []field
+	current := []embedded{}
+	next := []embedded{{typ: t}}
+	var count, nextCount map[reflect.Type]int
+	visited := map[reflect.Type]bool{}
+
+	for len(next) > 0 {
+		current, next = next, current[:0]
+		count, nextCount = nextCount, map[reflect.Type]int{}
+
+		for _, e := range current {
+			if visited[e.typ] {
+				continue
+			}
+			visited[e.typ] = true
+
+			for i := 0; i < e.typ.NumField(); i++ {
+				sf := e.typ.Field(i)
+				if sf.Anonymous {
+					ft := sf.Type
+					if ft.Kind() == reflect.Ptr {
+						ft = ft.Elem()
+					}
+					if !sf.IsExported() && ft.Kind() != reflect.Struct {
+						continue
+					}
+					// Embedded fields of unexported struct types may
+					// still have exported fields.
+				} else if !sf.IsExported() {
+					continue
+				}
+				// skip the helper InlineMarshaler field itself so it doesn't appear in output
+				if sf.Type == inlineMarshalerType {
+					continue
+				}
+
+				tag := lookupJSONTag(sf, tags.name)
+				if tag.omit {
+					continue
+				}
+				tagged := tag.name != ""
+				directives := parseInlineTag(sf, tags.directive)
+
+				index := make([]int, len(e.index)+1)
+				copy(index, e.index)
+				index[len(e.index)] = i
+				goPath := e.goPath + sf.Name
+
+				ft := sf.Type
+				if ft.Name() == "" && ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+
+				// Promote the fields of untagged embedded structs.
+				if sf.Anonymous && ft.Kind() == reflect.Struct && !tagged && !tag.inline && !directives.inline {
+					nextCount[ft]++
+					if nextCount[ft] == 1 {
+						next = append(next, embedded{typ: ft, index: index, goPath: goPath + "."})
+					}
+					continue
+				}
+				// Like encoding/json, keep tagged embedded structs of
+				// unexported types as named fields.
+				if !sf.IsExported() && !(sf.Anonymous && tagged) {
+					continue
+				}
+
+				f, err := newField(sf, tag, directives, index, goPath, tagged)
+				if err != nil {
+					return nil, err
+				}
+				fields = append(fields, f)
+				if count[e.typ] > 1 {
+					// If there were multiple instances, add a second,
+					// so that the annihilation code will see a duplicate.
+					fields = append(fields, fields[len(fields)-1])
+				}
+			}
+		}
+	}
+
+	fields = dominantFields(fields)
+
+	fs := &structFields{list: fields, discriminator: -1}
+	hasVariant := false
+	for i, f := range fs.list {
+		if f.variant != "" {
+			hasVariant = true
+		}
+		if !f.discriminator {
+			continue
+		}
+		if fs.discriminator >= 0 {
+			return nil, errors.New("jsoninline: multiple discriminator fields in " + t.String())
+		}
+		fs.discriminator = i
+	}
+	if hasVariant && fs.discriminator < 0 {
+		return nil, errors.New("jsoninline: variant fields without a discriminator in " + t.String())
+	}
+	return fs, nil
+}
+
+func newField(sf reflect.StructField, tag jsonTag, directives inlineTag, index []int, goPath string, tagged bool) (field, error) {
+	name := tag.name
+	if name == "" {
+		name = sf.Name
+	}
+	f := field{
+		name:          name,
+		nameBytes:     append(appendString(nil, name, true), ':'),
+		nameNonEsc:    append(appendString(nil, name, false), ':'),
+		goPath:        goPath,
+		index:         index,
+		tagged:        tagged,
+		typ:           sf.Type,
+		omitEmpty:     tag.omitEmpty,
+		omitZero:      tag.omitZero,
+		inline:        tag.inline || directives.inline,
+		variant:       directives.variant,
+		discriminator: directives.discriminator,
+		remain:        tag.remain || directives.remain,
+	}
+	if f.remain {
+		if f.inline {
+			return field{}, errors.New("jsoninline: field " + goPath + " cannot be both inline and remain")
+		}
+		if f.typ.Kind() != reflect.Map || f.typ.Key().Kind() != reflect.String {
+			return field{}, errors.New("jsoninline: remain field " + goPath + " must be a map with string keys")
+		}
+	}
+	f.isZero = isZeroFunc(f.typ)
+	if tag.asString {
+		// As in encoding/json, the string option only applies to
+		// scalars, possibly behind an unnamed pointer.
+		ft := f.typ
+		if ft.Name() == "" && ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		switch ft.Kind() {
+		case reflect.Bool,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+			reflect.Float32, reflect.Float64,
+			reflect.String:
+			f.quoted = true
+		}
+	}
+	if f.inline && f.typ.Kind() == reflect.Map && f.typ.Key().Kind() == reflect.String && !hasCodec(f.typ) {
+		// An inline map holds the keys its siblings leave, both ways.
+		f.inline, f.remain = false, true
+	}
+	if f.inline {
+		it := f.typ
+		for it.Kind() == reflect.Ptr {
+			it = it.Elem()
+		}
+		if it.Kind() == reflect.Struct && !hasCodec(it) {
+			f.inlineType = it
+		}
+	}
+	return f, nil
+}
+
+// dominantFields removes the fields hidden by Go's rules for embedded
+// fields, keeping the survivors in index order. Inline and remain fields
+// have no key of their own and compete by Go field name instead.
+func dominantFields(fields []field) []field {
+	byName := make(map[fieldName][]int)
+	for i, f := range fields {
+		byName[f.dominanceName()] = append(byName[f.dominanceName()], i)
+	}
+
+	out := make([]field, 0, len(fields))
+	seen := make(map[fieldName]bool)
+	for _, f := range fields {
+		name := f.dominanceName()
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		if j, ok := dominantField(fields, byName[name]); ok {
+			out = append(out, fields[j])
+		}
+	}
+	slices.SortStableFunc(out, func(a, b field) int {
+		return slices.Compare(a.index, b.index)
+	})
+	return out
+}
+
+// fieldName is the name under which a field competes with the fields of
+// other embedded structs: its key, or its Go field name if goName is set.
+type fieldName struct {
+	name   string
+	goName bool
+}
+
+func (f *field) dominanceName() fieldName {
+	if f.inline || f.remain {
+		return fieldName{name: f.goPath[strings.LastIndexByte(f.goPath, '.')+1:], goName: true}
+	}
+	return fieldName{name: f.name}
+}
+
+// dominantField picks the field that wins among candidates sharing a
+// name: the shallowest one, preferring tagged fields at equal depth. It
+// reports false when no single field wins.
+func dominantField(fields []field, candidates []int) (int, bool) {
+	best := candidates[0]
+	ambiguous := false
+	for _, j := range candidates[1:] {
+		a, b := fields[best], fields[j]
+		switch {
+		case len(b.index) < len(a.index), len(b.index) == len(a.index) && b.tagged && !a.tagged:
+			best, ambiguous = j, false
+		case len(b.index) == len(a.index) && b.tagged == a.tagged:
+			ambiguous = true
+		}
+	}
+	return best, !ambiguous
+}
+
+// expandKeys records the keys produced by fs into keys, following inline
+// struct fields, and the first key of each case-folded form into folded. It
+// reports whether fs has inline fields whose keys are
+// only known at runtime, and whether it has remain fields.
+func expandKeys(fs *structFields, tags tagNames, prefix string, variants []variantChoice, visiting map[reflect.Type]bool, keys map[string][]keySource, folded map[string]string) (dynamic, remain bool, err error) {
+	for _, f := range fs.list {
+		path := prefix + f.goPath
+		choices := variants
+		if f.variant != "" {
+			choices = append(slices.Clip(variants), variantChoice{owner: prefix, value: f.variant})
+		}
+		if f.remain {
+			remain = true
+			continue
+		}
+		if !f.inline {
+			keys[f.name] = append(keys[f.name], keySource{path: path, typ: f.typ, variants: choices})
+			k := foldName(f.name)
+			if _, ok := folded[k]; !ok {
+				folded[k] = f.name
+			}
+			continue
+		}
+		if f.inlineType == nil {
+			dynamic = true
+			continue
+		}
+		if visiting[f.inlineType] {
+			return false, false, errors.New("jsoninline: inline cycle through " + f.inlineType.String())
+		}
+		sub, err := fieldList(f.inlineType, tags)
+		if err != nil {
+			return false, false, err
+		}
+		visiting[f.inlineType] = true
+		d, r, err := expandKeys(sub, tags, path+".", choices, visiting, keys, folded)
+		delete(visiting, f.inlineType)
+		if err != nil {
+			return false, false, err
+		}
+		dynamic = dynamic || d
+		remain = remain || r
+	}
+	return dynamic, remain, nil
+}
+
+// hasCodec reports whether t or *t implements any of json.Marshaler,
+// json.Unmarshaler, encoding.TextMarshaler or encoding.TextUnmarshaler.
+func hasCodec(t reflect.Type) bool {
+	pt := reflect.PointerTo(t)
+	return pt.Implements(marshalerType) || pt.Implements(textMarshalerType) ||
+		pt.Implements(unmarshalerType) || pt.Implements(textUnmarshalerType)
+}
+
+// fieldByIndex returns the field of the struct v at index. It reports false
+// if the field is reached through a nil embedded pointer.
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// fieldByIndexAlloc is like fieldByIndex but allocates nil embedded
+// pointers on the way to the field.
+func fieldByIndexAlloc(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, errors.New("jsoninline: cannot set embedded pointer to unexported struct " + v.Type().Elem().String())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
+
+type isZeroer interface {
+	IsZero() bool
+}
+
+var isZeroerType = reflect.TypeFor[isZeroer]()
+
+// isZeroFunc returns a function calling the IsZero method of values of type
+// t, or nil if t has none. Like encoding/json, it treats nil pointers and
+// interfaces as zero instead of calling the method on them.
+func isZeroFunc(t reflect.Type) func(reflect.Value) bool {
+	switch {
+	case t.Kind() == reflect.Interface && t.Implements(isZeroerType):
+		return func(v reflect.Value) bool {
+			return v.IsNil() ||
+				(v.Elem().Kind() == reflect.Pointer && v.Elem().IsNil()) ||
+				v.Interface().(isZeroer).IsZero()
+		}
+	case t.Kind() == reflect.Pointer && t.Implements(isZeroerType):
+		return func(v reflect.Value) bool {
+			return v.IsNil() || v.Interface().(isZeroer).IsZero()
+		}
+	case t.Implements(isZeroerType):
+		return func(v reflect.Value) bool {
+			return v.Interface().(isZeroer).IsZero()
+		}
+	case reflect.PointerTo(t).Implements(isZeroerType):
+		return func(v reflect.Value) bool {
+			if !v.CanAddr() {
+				// Temporarily box v so we can take the address.
+				v2 := reflect.New(v.Type()).Elem()
+				v2.Set(v)
+				v = v2
+			}
+			return v.Addr().Interface().(isZeroer).IsZero()
+		}
+	}
+	return nil
+}
+
+// omit reports whether the field value v is left out of the encoded object
+// because of its omitempty or omitzero option, set by its tag or by opts.
+func (f *field) omit(v reflect.Value, opts *Options) bool {
+	if (f.omitEmpty || opts.OmitEmpty) && isEmptyValue(v) {
+		return true
+	}
+	if f.omitZero || opts.OmitZero {
+		if f.isZero != nil {
+			return f.isZero(v)
+		}
+		return v.IsZero()
+	}
+	return false
+}
+
+// isEmptyValue reports whether v is empty in the sense of omitempty.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
+
+// foldName returns s in a case-folded form, so that two names match
+// case-insensitively exactly when their folded forms are equal.
+func foldName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < utf8.RuneSelf {
+			if 'a' <= r && r <= 'z' {
+				r -= 'a' - 'A'
+			}
+			return r
+		}
+		return unicode.ToUpper(unicode.ToLower(r))
+	}, s)
+}
+
+var inlineMarshalerType = reflect.TypeFor[InlineMarshaler]()
@@ -1,7 +1,6 @@
 package jsoninline
 
 import (
-	"encoding/json"
 	"errors"
 	"reflect"
 )
@@ -12,10 +11,24 @@ func V(v any) *InlineMarshaler {
 
 type InlineMarshaler struct {
 	V any
+
+	// Options configures encoding and decoding of V. Nil means the
+	// defaults.
+	Options *Options
+}
+
+// inlineValue returns the value to encode and its options, if any.
+func (im InlineMarshaler) inlineValue() (any, *Options) {
+	return im.V, im.Options
+}
+
+// inlineTarget returns the pointer to decode into and its options, if any.
+func (im *InlineMarshaler) inlineTarget() (any, *Options) {
+	return im.V, im.Options
 }
 
 func (im InlineMarshaler) MarshalJSON() ([]byte, error) {
-	return marshal(im.V)
+	return marshal(im.V, im.Options.options())
 }
 
 // UnmarshalJSON implements json.Unmarshaler for InlineMarshaler.
@@ -24,279 +37,76 @@ func (im *InlineMarshaler) UnmarshalJSON(data []byte) error {
 	if im == nil || im.V == nil {
 		return errors.New("jsoninline: nil target for UnmarshalJSON")
 	}
-	return unmarshal(data, im.V)
+	return unmarshal(data, im.V, im.Options.options())
 }
 
-func marshal(p any) ([]byte, error) {
-	v := reflect.ValueOf(p)
-	if v.Kind() == reflect.Ptr {
-		v = v.Elem()
-	}
-
-	// handle slices/arrays by marshaling each element individually
-	if v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
-		out := make([]interface{}, 0, v.Len())
-		for i := 0; i < v.Len(); i++ {
-			elem := v.Index(i).Interface()
-			b, err := marshal(elem)
-			if err != nil {
-				return nil, err
-			}
-			var val interface{}
-			if err := json.Unmarshal(b, &val); err != nil {
-				return nil, err
-			}
-			out = append(out, val)
-		}
-		return json.Marshal(out)
-	}
-
-	// for non-structs fallback to default marshal
-	if v.Kind() != reflect.Struct {
-		return json.Marshal(p)
-	}
-
-	m := make(map[string]any)
-	t := v.Type()
-
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		if field.PkgPath != "" { // unexported
-			continue
-		}
-
-		// skip the helper InlineStruct field itself so it doesn't appear in output
-		if field.Type == reflect.TypeOf(InlineMarshaler{}) {
-			continue
-		}
-
-		info := fieldJSONInfo(field)
-		if info.omit {
-			continue
-		}
-
-		name := info.name
-		if name == "" {
-			name = field.Name
-		}
-
-		fv := v.Field(i)
-
-		// handle inline fields
-		if info.settings["inline"] {
-			if fv.Kind() == reflect.Ptr {
-				if fv.IsNil() {
-					continue
-				}
-			}
-			inlineBytes, err := marshal(fv.Interface())
-			if err != nil {
-				return nil, err
-			}
-			var inlineMap map[string]interface{}
-			if err := json.Unmarshal(inlineBytes, &inlineMap); err != nil {
-				return nil, err
-			}
-			for k, v := range inlineMap {
-				m[k] = v
-			}
-			if name != "" {
-				delete(m, name)
-			}
-			continue
-		}
-
-		if fv.Kind() == reflect.Ptr && fv.IsNil() {
-			if info.settings["omitempty"] {
-				continue
-			}
-			m[name] = nil
-			continue
-		}
-
-		valBytes, err := json.Marshal(fv.Interface())
-		if err != nil {
-			return nil, err
-		}
-		var val interface{}
-		if err := json.Unmarshal(valBytes, &val); err != nil {
-			return nil, err
-		}
-		m[name] = val
-	}
-
-	return json.Marshal(m)
+// Marshal returns the JSON encoding of v with inline fields flattened into
+// their parents, as json.Marshal(V(v)) does.
+func Marshal[T any](v T) ([]byte, error) {
+	return marshal(v, Options{})
 }
 
-func unmarshal(data []byte, p any) error {
-	v := reflect.ValueOf(p)
-	if v.Kind() != reflect.Ptr {
-		return errors.New("jsoninline: V must be a pointer")
-	}
-
-	ve := v.Elem()
-
-	// handle slices/arrays
-	if ve.Kind() == reflect.Slice || ve.Kind() == reflect.Array {
-		var raws []json.RawMessage
-		if err := json.Unmarshal(data, &raws); err != nil {
-			return err
-		}
-
-		elemType := ve.Type().Elem()
-
-		if ve.Kind() == reflect.Slice {
-			slice := reflect.MakeSlice(ve.Type(), 0, len(raws))
-			for _, raw := range raws {
-				var elemVal reflect.Value
-				if elemType.Kind() == reflect.Ptr && elemType.Elem().Kind() == reflect.Struct {
-					// element is pointer to struct (*T)
-					newElem := reflect.New(elemType.Elem()) // *T
-					im := &InlineMarshaler{V: newElem.Interface()}
-					if err := im.UnmarshalJSON(raw); err != nil {
-						return err
-					}
-					elemVal = newElem
-				} else if elemType.Kind() == reflect.Struct {
-					// element is struct (T)
-					newElemPtr := reflect.New(elemType) // *T
-					im := &InlineMarshaler{V: newElemPtr.Interface()}
-					if err := im.UnmarshalJSON(raw); err != nil {
-						return err
-					}
-					elemVal = newElemPtr.Elem()
-				} else {
-					// other types
-					newElemPtr := reflect.New(elemType)
-					if err := unmarshalRaw(raw, newElemPtr.Interface()); err != nil {
-						return err
-					}
-					elemVal = newElemPtr.Elem()
-				}
-				slice = reflect.Append(slice, elemVal)
-			}
-			ve.Set(slice)
-			return nil
-		}
-
-		// array
-		if len(raws) != ve.Len() {
-			return errors.New("jsoninline: array length mismatch")
-		}
-		for i, raw := range raws {
-			elemVal, err := decodeElement(raw, elemType)
-			if err != nil {
-				return err
-			}
-			ve.Index(i).Set(elemVal)
-		}
-		return nil
-	}
-
-	// non-struct fallback to default unmarshal
-	if ve.Kind() != reflect.Struct {
-		return json.Unmarshal(data, p)
-	}
-
-	// struct: parse top-level map and populate fields, handling ",inline" tags
-	top := make(map[string]json.RawMessage)
-	if err := json.Unmarshal(data, &top); err != nil {
-		return err
-	}
-
-	t := ve.Type()
-	out := reflect.New(t).Elem()
-
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		if field.PkgPath != "" { // unexported
-			continue
-		}
-		if field.Type == reflect.TypeOf(InlineMarshaler{}) {
-			continue
-		}
+// Unmarshal decodes data into a new value of type T, flattening inline
+// fields as json.Unmarshal(data, V(&v)) does.
+func Unmarshal[T any](data []byte) (T, error) {
+	var v T
+	err := unmarshal(data, &v, Options{})
+	return v, err
+}
 
-		info := fieldJSONInfo(field)
-		if info.omit {
-			continue
-		}
+// MarshalOptions is like Marshal but configured by opts. Nil opts means the
+// defaults.
+func MarshalOptions(v any, opts *Options) ([]byte, error) {
+	return marshal(v, opts.options())
+}
 
-		name := info.name
-		if name == "" {
-			name = field.Name
-		}
+// UnmarshalOptions decodes data into the value pointed to by v, configured
+// by opts. Nil opts means the defaults.
+func UnmarshalOptions(data []byte, v any, opts *Options) error {
+	return unmarshal(data, v, opts.options())
+}
 
-		fv := out.Field(i)
+// Inline holds a value that is encoded and decoded with inline semantics
+// wherever it appears, for example as a field of a struct handled by
+// encoding/json. Inside a value encoded or decoded by this package it uses
+// the options of that value.
+type Inline[T any] struct {
+	V T
+}
 
-		if info.settings["inline"] {
-			// For inline fields, unmarshal the whole object into the inline struct.
-			if fv.Kind() == reflect.Ptr {
-				fv.Set(reflect.New(fv.Type().Elem()))
-				if err := unmarshal(data, fv.Interface()); err != nil {
-					return err
-				}
-			} else {
-				if err := unmarshal(data, fv.Addr().Interface()); err != nil {
-					return err
-				}
-			}
-			continue
-		}
+func (i Inline[T]) inlineValue() (any, *Options) {
+	return i.V, nil
+}
 
-		raw, ok := top[name]
-		if !ok {
-			// not present in JSON; leave zero value (or nil pointer)
-			continue
-		}
+func (i *Inline[T]) inlineTarget() (any, *Options) {
+	return &i.V, nil
+}
 
-		if fv.Kind() == reflect.Ptr {
-			fv.Set(reflect.New(fv.Type().Elem()))
-			if err := json.Unmarshal(raw, fv.Interface()); err != nil {
-				return err
-			}
-		} else {
-			if err := json.Unmarshal(raw, fv.Addr().Interface()); err != nil {
-				return err
-			}
-		}
-	}
+// valueType lets schema generation describe Inline as its value.
+func (Inline[T]) valueType() reflect.Type {
+	return reflect.TypeFor[T]()
+}
 
-	ve.Set(out)
-	return nil
+// MarshalJSON implements json.Marshaler for Inline.
+func (i Inline[T]) MarshalJSON() ([]byte, error) {
+	return marshal(i.V, Options{})
 }
 
-// unmarshalRaw raw JSON into target, handling structs with inline tags recursively.
-func unmarshalRaw(raw json.RawMessage, v interface{}) error {
-	// If target implements json.Unmarshaler, let json.Unmarshal handle it.
-	if um, ok := v.(json.Unmarshaler); ok {
-		return um.UnmarshalJSON(raw)
-	}
-	return json.Unmarshal(raw, v)
+// UnmarshalJSON implements json.Unmarshaler for Inline.
+func (i *Inline[T]) UnmarshalJSON(data []byte) error {
+	return unmarshal(data, &i.V, Options{})
 }
 
-// decodeElement decodes a single JSON element into a reflect.Value suitable
-// for appending to a slice or setting into an array index. It handles
-// pointer-to-struct, struct, and other primitive/complex types uniformly.
-func decodeElement(raw json.RawMessage, elemType reflect.Type) (reflect.Value, error) {
-	if elemType.Kind() == reflect.Ptr && elemType.Elem().Kind() == reflect.Struct {
-		newElem := reflect.New(elemType.Elem())
-		im := &InlineMarshaler{V: newElem.Interface()}
-		if err := im.UnmarshalJSON(raw); err != nil {
-			return reflect.Value{}, err
-		}
-		return newElem, nil
-	} else if elemType.Kind() == reflect.Struct {
-		newElemPtr := reflect.New(elemType)
-		im := &InlineMarshaler{V: newElemPtr.Interface()}
-		if err := im.UnmarshalJSON(raw); err != nil {
-			return reflect.Value{}, err
-		}
-		return newElemPtr.Elem(), nil
-	}
+// inlineValuer and inlineTargeter are implemented by the wrappers of this
+// package, so that a wrapper nested in a value being encoded or decoded is
+// handled in place, with the options of the outer value unless it has its
+// own.
+type (
+	inlineValuer   interface{ inlineValue() (any, *Options) }
+	inlineTargeter interface{ inlineTarget() (any, *Options) }
+)
 
-	newElemPtr := reflect.New(elemType)
-	if err := unmarshalRaw(raw, newElemPtr.Interface()); err != nil {
-		return reflect.Value{}, err
-	}
-	return newElemPtr.Elem(), nil
-}
+var (
+	inlineValuerType   = reflect.TypeFor[inlineValuer]()
+	inlineTargeterType = reflect.TypeFor[inlineTargeter]()
+)
@@ -2,8 +2,15 @@ package jsoninline_test
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/jsonschema-go/jsonschema"
 	"github.com/hydrz/jsoninline"
@@ -175,8 +182,591 @@ func TestSchemaForSlice(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to generate schema: %v", err)
 	}
-	// Basic sanity: ensure schema properties contain inlined fields
-	if _, ok := schema.Properties["city"]; !ok {
-		t.Fatalf("expected schema to include 'city' property; got properties: %v", schema.Properties)
+	// Basic sanity: ensure the alternatives of the inline fields contain
+	// their properties
+	if len(schema.AllOf) != 2 || len(schema.AllOf[1].AnyOf) == 0 || len(schema.AllOf[1].AnyOf[0].AllOf) == 0 {
+		t.Fatalf("expected inline fields as anyOf alternatives; got %+v", schema)
+	}
+	if _, ok := schema.AllOf[1].AnyOf[0].AllOf[0].Properties["city"]; !ok {
+		t.Fatalf("expected schema to include 'city' property; got properties: %v", schema.AllOf[1].AnyOf[0].AllOf[0].Properties)
+	}
+}
+
+// TestMarshalFieldOrder ensures keys are written in struct declaration order,
+// with inlined fields spliced in at the position of their inline field.
+func TestMarshalFieldOrder(t *testing.T) {
+	u := User{
+		ID:    12,
+		Name:  "Ordered",
+		Email: "ordered@example.com",
+		China: &China{
+			City:     "Shenzhen",
+			Province: "Guangdong",
+		},
+		USA: &USA{
+			City:  "Austin",
+			State: "Texas",
+		},
+	}
+
+	b, err := json.Marshal(jsoninline.V(u))
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+
+	want := `{"id":12,"name":"Ordered","email":"ordered@example.com","city":"Austin","province":"Guangdong","state":"Texas"}`
+	if string(b) != want {
+		t.Fatalf("unexpected output\n got: %s\nwant: %s", b, want)
+	}
+}
+
+type Directory struct {
+	Owner   User             `json:"owner"`
+	Backup  *User            `json:"backup,omitempty"`
+	Members []User           `json:"members"`
+	ByTag   map[string]*User `json:"by_tag"`
+}
+
+// TestNestedInlineFields ensures inline tags are honored in structs reached
+// through regular fields, pointers, slices and maps, in both directions.
+func TestNestedInlineFields(t *testing.T) {
+	d := Directory{
+		Owner:   User{ID: 1, Name: "Alice", China: &China{City: "Shenzhen"}},
+		Backup:  &User{ID: 2, Name: "Bob", USA: &USA{State: "Texas"}},
+		Members: []User{{ID: 3, Name: "Carol", China: &China{Province: "Sichuan"}}},
+		ByTag:   map[string]*User{"ops": {ID: 4, Name: "Dan", USA: &USA{City: "Austin"}}},
+	}
+
+	b, err := json.Marshal(jsoninline.V(d))
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	out := string(b)
+	for _, s := range []string{`"China"`, `"USA"`} {
+		if strings.Contains(out, s) {
+			t.Fatalf("did not expect %s in output: %s", s, out)
+		}
+	}
+	for _, s := range []string{`"city":"Shenzhen"`, `"state":"Texas"`, `"province":"Sichuan"`, `"city":"Austin"`} {
+		if !strings.Contains(out, s) {
+			t.Fatalf("expected %s in output: %s", s, out)
+		}
+	}
+
+	var got Directory
+	if err := json.Unmarshal(b, jsoninline.V(&got)); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if got.Owner.China == nil || got.Owner.China.City != "Shenzhen" {
+		t.Fatalf("unexpected owner: %+v", got.Owner.China)
+	}
+	if got.Backup == nil || got.Backup.USA == nil || got.Backup.USA.State != "Texas" {
+		t.Fatalf("unexpected backup: %+v", got.Backup)
+	}
+	if len(got.Members) != 1 || got.Members[0].China == nil || got.Members[0].China.Province != "Sichuan" {
+		t.Fatalf("unexpected members: %+v", got.Members)
+	}
+	if u := got.ByTag["ops"]; u == nil || u.USA == nil || u.USA.City != "Austin" {
+		t.Fatalf("unexpected by_tag: %+v", got.ByTag)
+	}
+}
+
+// Labels encodes itself as a JSON object of "label_<key>" members.
+type Labels map[string]string
+
+func (l Labels) MarshalJSON() ([]byte, error) {
+	m := make(map[string]string, len(l))
+	for k, v := range l {
+		m["label_"+k] = v
+	}
+	return json.Marshal(m)
+}
+
+func (l *Labels) UnmarshalJSON(data []byte) error {
+	var m map[string]any
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*l = make(Labels)
+	for k, v := range m {
+		if name, ok := strings.CutPrefix(k, "label_"); ok {
+			(*l)[name], _ = v.(string)
+		}
+	}
+	return nil
+}
+
+// Window has its own codec and must not be torn apart field by field.
+type Window struct {
+	From, To int
+}
+
+func (w Window) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{"window": strconv.Itoa(w.From) + "-" + strconv.Itoa(w.To)})
+}
+
+func (w *Window) UnmarshalJSON(data []byte) error {
+	var m map[string]string
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	from, to, _ := strings.Cut(m["window"], "-")
+	w.From, _ = strconv.Atoi(from)
+	w.To, _ = strconv.Atoi(to)
+	return nil
+}
+
+type Schedule struct {
+	Name   string    `json:"name"`
+	Window Window    `json:",inline"`
+	Labels Labels    `json:",inline"`
+	At     time.Time `json:"at"`
+}
+
+// TestInlineCustomCodecs ensures inline values with their own JSON codec
+// are merged through MarshalJSON and populated through UnmarshalJSON.
+func TestInlineCustomCodecs(t *testing.T) {
+	s := Schedule{
+		Name:   "nightly",
+		Window: Window{From: 1, To: 5},
+		Labels: Labels{"team": "infra"},
+		At:     time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC),
+	}
+
+	b, err := json.Marshal(jsoninline.V(s))
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	want := `{"name":"nightly","window":"1-5","label_team":"infra","at":"2024-05-06T07:08:09Z"}`
+	if string(b) != want {
+		t.Fatalf("unexpected output\n got: %s\nwant: %s", b, want)
+	}
+
+	var got Schedule
+	if err := json.Unmarshal(b, jsoninline.V(&got)); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if got.Window != s.Window || got.Labels["team"] != "infra" || !got.At.Equal(s.At) {
+		t.Fatalf("unexpected result: %+v", got)
+	}
+}
+
+// TestTopLevelCustomCodec ensures a value implementing json.Marshaler is
+// encoded through its own method rather than reflected into.
+func TestTopLevelCustomCodec(t *testing.T) {
+	b, err := json.Marshal(jsoninline.V([]Window{{From: 2, To: 3}}))
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if string(b) != `[{"window":"2-3"}]` {
+		t.Fatalf("unexpected output: %s", b)
+	}
+}
+
+type ServerOptions struct {
+	Server     string `json:"server,omitempty"`
+	ServerPort int    `json:"server_port,omitempty"`
+}
+
+type DialerOptions struct {
+	Timeout int    `json:"timeout,omitempty"`
+	Server  string `json:"server,omitempty"` // same depth as ServerOptions.Server: both are dropped
+}
+
+type TaggedServer struct {
+	Name string `json:"name"`
+}
+
+type hiddenTagged struct {
+	S int `json:"s"`
+}
+
+type EmbeddingOptions struct {
+	ServerOptions
+	*DialerOptions
+	TaggedServer `json:"tagged"`
+	hiddenTagged `json:"hidden"` // unexported but tagged: a named field
+	Tag          string          `json:"tag"`
+}
+
+// TestEmbeddedStructs ensures fields of embedded structs are promoted the
+// way encoding/json promotes them, including its dominance rules.
+func TestEmbeddedStructs(t *testing.T) {
+	tests := []EmbeddingOptions{
+		{ServerOptions: ServerOptions{Server: "1.1.1.1", ServerPort: 53}, Tag: "udp"},
+		{ServerOptions: ServerOptions{ServerPort: 853}, DialerOptions: &DialerOptions{Timeout: 5, Server: "x"},
+			TaggedServer: TaggedServer{Name: "t"}},
+		{hiddenTagged: hiddenTagged{S: 1}, Tag: "hidden"},
+	}
+	for _, v := range tests {
+		got, err := json.Marshal(jsoninline.V(v))
+		if err != nil {
+			t.Fatalf("marshal failed: %v", err)
+		}
+		want, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("encoding/json marshal failed: %v", err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("output differs from encoding/json\n got: %s\nwant: %s", got, want)
+		}
+
+		var decoded EmbeddingOptions
+		if err := json.Unmarshal(got, jsoninline.V(&decoded)); err != nil {
+			t.Fatalf("unmarshal failed: %v", err)
+		}
+		var stdDecoded EmbeddingOptions
+		if err := json.Unmarshal(want, &stdDecoded); err != nil {
+			t.Fatalf("encoding/json unmarshal failed: %v", err)
+		}
+		if !reflect.DeepEqual(decoded, stdDecoded) {
+			t.Errorf("decoded value differs from encoding/json\n got: %+v\nwant: %+v", decoded, stdDecoded)
+		}
+	}
+}
+
+type CommonDialer struct {
+	Kind   string         `jsoninline:"discriminator"` // untagged, as vet rejects a json tag reached twice
+	Dialer *DialerOptions `json:",inline"`
+	UDP    *ServerOptions `json:",inline" jsoninline:"variant=udp"`
+}
+
+type EmbedsCommonA struct {
+	CommonDialer
+}
+
+type EmbedsCommonB struct {
+	CommonDialer
+}
+
+type EmbeddedTwice struct {
+	EmbedsCommonA
+	EmbedsCommonB
+	Tag string `json:"tag"`
+}
+
+// TestEmbeddedTwice ensures inline fields of a struct embedded twice at the
+// same depth cancel each other out like any other field of it.
+func TestEmbeddedTwice(t *testing.T) {
+	common := CommonDialer{Kind: "udp", Dialer: &DialerOptions{Timeout: 5}, UDP: &ServerOptions{Server: "s"}}
+	v := EmbeddedTwice{EmbedsCommonA{common}, EmbedsCommonB{common}, "t"}
+	got, err := jsoninline.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	want, _ := json.Marshal(v)
+	if string(got) != string(want) {
+		t.Fatalf("output differs from encoding/json\n got: %s\nwant: %s", got, want)
+	}
+	if err := jsoninline.CheckConflicts(reflect.TypeFor[EmbeddedTwice]()); err != nil {
+		t.Fatalf("unexpected conflict: %v", err)
+	}
+	opts := &jsoninline.Options{ConflictPolicy: jsoninline.ErrorOnConflict}
+	var decoded EmbeddedTwice
+	if err := jsoninline.UnmarshalOptions([]byte(`{"tag":"t","timeout":5,"server":"s"}`), &decoded, opts); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if decoded.Tag != "t" || decoded.EmbedsCommonA.Dialer != nil || decoded.EmbedsCommonB.Dialer != nil {
+		t.Fatalf("unexpected value: %+v", decoded)
+	}
+}
+
+type hiddenEmb struct {
+	Secret string
+}
+
+type HideTop struct {
+	hiddenEmb `json:"-"`
+	Name      string
+}
+
+// TestEmbeddedSkipped ensures an unexported embedded struct tagged "-" is
+// skipped rather than promoted, as in encoding/json.
+func TestEmbeddedSkipped(t *testing.T) {
+	v := HideTop{hiddenEmb: hiddenEmb{Secret: "s"}, Name: "n"}
+	got, err := jsoninline.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	want, _ := json.Marshal(v)
+	if string(got) != string(want) {
+		t.Fatalf("output differs from encoding/json\n got: %s\nwant: %s", got, want)
+	}
+
+	decoded, err := jsoninline.Unmarshal[HideTop]([]byte(`{"Secret":"s","Name":"n"}`))
+	if err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if decoded.Secret != "" || decoded.Name != "n" {
+		t.Fatalf("unexpected value: %+v", decoded)
+	}
+}
+
+type UDPServerOptions struct {
+	ServerOptions
+	Dialer DialerOptions `json:",inline"`
+}
+
+type EmbeddedInline struct {
+	Type string            `json:"type"`
+	UDP  *UDPServerOptions `json:",inline"`
+}
+
+// TestEmbeddedInlineSchema ensures encode, decode and schema agree on the
+// flattened shape of an inline struct with an embedded struct.
+func TestEmbeddedInlineSchema(t *testing.T) {
+	v := EmbeddedInline{
+		Type: "udp",
+		UDP: &UDPServerOptions{
+			ServerOptions: ServerOptions{Server: "1.1.1.1", ServerPort: 53},
+			Dialer:        DialerOptions{Timeout: 10},
+		},
+	}
+	b, err := json.Marshal(jsoninline.V(v))
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	want := `{"type":"udp","server":"1.1.1.1","server_port":53,"timeout":10}`
+	if string(b) != want {
+		t.Fatalf("unexpected output\n got: %s\nwant: %s", b, want)
+	}
+
+	var got EmbeddedInline
+	if err := json.Unmarshal(b, jsoninline.V(&got)); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if got.UDP == nil || got.UDP.Server != "1.1.1.1" || got.UDP.Dialer.Timeout != 10 {
+		t.Fatalf("unexpected result: %+v", got.UDP)
+	}
+
+	schema, err := jsoninline.For[EmbeddedInline](&jsonschema.ForOptions{})
+	if err != nil {
+		t.Fatalf("Failed to generate schema: %v", err)
+	}
+	udp := schema.AllOf[1].AnyOf[0].AllOf[0]
+	props := slices.Sorted(maps.Keys(udp.Properties))
+	if !slices.Equal(props, []string{"server", "server_port"}) {
+		t.Fatalf("unexpected UDP properties: %v", props)
+	}
+}
+
+type Document struct {
+	Version int                       `json:"version"`
+	Users   jsoninline.Inline[[]User] `json:"users"`
+	Owner   *jsoninline.Inline[*User] `json:"owner,omitempty"`
+}
+
+// TestGenericHelpers ensures Marshal, Unmarshal and Inline apply inline
+// semantics without wrapping values in V.
+func TestGenericHelpers(t *testing.T) {
+	u := User{ID: 1, Name: "Alice", China: &China{City: "Shenzhen"}}
+	b, err := jsoninline.Marshal(u)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	want := `{"id":1,"name":"Alice","email":"","city":"Shenzhen"}`
+	if string(b) != want {
+		t.Fatalf("unexpected output\n got: %s\nwant: %s", b, want)
+	}
+
+	got, err := jsoninline.Unmarshal[User](b)
+	if err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if got.ID != 1 || got.China == nil || got.China.City != "Shenzhen" {
+		t.Fatalf("unexpected value: %+v", got)
+	}
+
+	// Inline works as a field of a struct handled by encoding/json.
+	doc := Document{Version: 2, Users: jsoninline.Inline[[]User]{V: []User{u}}}
+	b, err = json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	want = `{"version":2,"users":[{"id":1,"name":"Alice","email":"","city":"Shenzhen"}]}`
+	if string(b) != want {
+		t.Fatalf("unexpected output\n got: %s\nwant: %s", b, want)
+	}
+
+	var decoded Document
+	data := `{"version":2,"users":[{"id":1,"city":"Austin","state":"TX"}],"owner":{"id":3,"city":"Paris"}}`
+	if err := json.Unmarshal([]byte(data), &decoded); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if len(decoded.Users.V) != 1 || decoded.Users.V[0].USA == nil || decoded.Users.V[0].USA.State != "TX" {
+		t.Fatalf("unexpected users: %+v", decoded.Users.V)
+	}
+	if decoded.Owner == nil || decoded.Owner.V == nil || decoded.Owner.V.China == nil || decoded.Owner.V.China.City != "Paris" {
+		t.Fatalf("unexpected owner: %+v", decoded.Owner)
+	}
+
+	schema, err := jsoninline.For[Document](nil)
+	if err != nil {
+		t.Fatalf("schema failed: %v", err)
+	}
+	users := schema.Properties["users"]
+	if users == nil || users.Items == nil || users.Items.AllOf == nil {
+		t.Fatalf("users should be described as inline users: %+v", users)
+	}
+}
+
+type Region struct {
+	Cloud, Name string
+}
+
+func (r Region) MarshalText() ([]byte, error) {
+	return []byte(r.Cloud + "/" + r.Name), nil
+}
+
+func (r *Region) UnmarshalText(b []byte) error {
+	cloud, name, ok := strings.Cut(string(b), "/")
+	if !ok {
+		return fmt.Errorf("bad region %q", b)
+	}
+	*r = Region{cloud, name}
+	return nil
+}
+
+type Fleet struct {
+	ByName   map[string]User   `json:"by_name"`
+	ByID     map[int]*User     `json:"by_id"`
+	ByRegion map[Region][]User `json:"by_region"`
+}
+
+// TestMapsOfStructs ensures map values are encoded and decoded with inline
+// semantics for string, integer and TextMarshaler keys.
+func TestMapsOfStructs(t *testing.T) {
+	alice := User{ID: 1, Name: "Alice", China: &China{City: "Shenzhen"}}
+	bob := User{ID: 2, Name: "Bob", USA: &USA{State: "TX"}}
+	fleet := Fleet{
+		ByName:   map[string]User{"bob": bob, "alice": alice},
+		ByID:     map[int]*User{10: &bob, 2: &alice},
+		ByRegion: map[Region][]User{{"aws", "us-east-1"}: {bob}, {"ali", "cn-shenzhen"}: {alice}},
+	}
+	b, err := jsoninline.Marshal(fleet)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	a := `{"id":1,"name":"Alice","email":"","city":"Shenzhen"}`
+	o := `{"id":2,"name":"Bob","email":"","state":"TX"}`
+	want := `{"by_name":{"alice":` + a + `,"bob":` + o + `},` +
+		`"by_id":{"10":` + o + `,"2":` + a + `},` +
+		`"by_region":{"ali/cn-shenzhen":[` + a + `],"aws/us-east-1":[` + o + `]}}`
+	if string(b) != want {
+		t.Fatalf("unexpected output\n got: %s\nwant: %s", b, want)
+	}
+
+	got, err := jsoninline.Unmarshal[Fleet](b)
+	if err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	// city is claimed by both China and USA, so compare the encoding.
+	if b2, _ := jsoninline.Marshal(got); string(b2) != want {
+		t.Fatalf("round trip mismatch\n got: %s\nwant: %s", b2, want)
+	}
+	if u := got.ByID[10]; u == nil || u.USA == nil || u.USA.State != "TX" {
+		t.Fatalf("unexpected by_id: %+v", got.ByID)
+	}
+	if us := got.ByRegion[Region{"ali", "cn-shenzhen"}]; len(us) != 1 || us[0].China == nil || us[0].China.City != "Shenzhen" {
+		t.Fatalf("unexpected by_region: %+v", got.ByRegion)
+	}
+
+	// Maps work at the top level too.
+	b, err = json.Marshal(jsoninline.V(map[uint8]User{7: alice}))
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if want := `{"7":` + a + `}`; string(b) != want {
+		t.Fatalf("unexpected output\n got: %s\nwant: %s", b, want)
+	}
+	var byID map[uint8]*User
+	if err := json.Unmarshal(b, jsoninline.V(&byID)); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if u := byID[7]; u == nil || u.China == nil || u.China.City != "Shenzhen" {
+		t.Fatalf("unexpected value: %+v", byID)
+	}
+
+	for _, data := range []string{`{"300":{}}`, `{"x":{}}`} {
+		err := jsoninline.UnmarshalOptions([]byte(data), &byID, nil)
+		var fe *jsoninline.FieldError
+		if !errors.As(err, &fe) {
+			t.Fatalf("%s: expected FieldError, got %v", data, err)
+		}
+		var te *json.UnmarshalTypeError
+		if !errors.As(err, &te) {
+			t.Fatalf("%s: expected UnmarshalTypeError, got %v", data, err)
+		}
+	}
+	if _, err := jsoninline.Unmarshal[map[Region]User]([]byte(`{"nowhere":{}}`)); err == nil {
+		t.Fatal("expected error for a bad TextUnmarshaler key")
+	}
+}
+
+type Roster struct {
+	Teams   [][]User          `json:"teams"`
+	Pair    [2]*User          `json:"pair"`
+	Regions map[string][]User `json:"regions"`
+	Backup  *[]User           `json:"backup"`
+}
+
+// TestContainerNesting ensures inline semantics reach structs through any
+// mix of slices, arrays, pointers and maps.
+func TestContainerNesting(t *testing.T) {
+	a := `{"id":1,"name":"Alice","email":"","province":"GD"}`
+	o := `{"id":2,"name":"Bob","email":"","state":"TX"}`
+	data := `{"teams":[[` + a + `,` + o + `],[]],"pair":[` + o + `,null],` +
+		`"regions":{"cn":[` + a + `]},"backup":[` + o + `]}`
+
+	got, err := jsoninline.Unmarshal[Roster]([]byte(data))
+	if err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if len(got.Teams) != 2 || len(got.Teams[0]) != 2 || got.Teams[0][0].China == nil || got.Teams[0][0].China.Province != "GD" {
+		t.Fatalf("unexpected teams: %+v", got.Teams)
+	}
+	if got.Pair[0] == nil || got.Pair[0].USA == nil || got.Pair[0].USA.State != "TX" || got.Pair[1] != nil {
+		t.Fatalf("unexpected pair: %+v", got.Pair)
+	}
+	if us := got.Regions["cn"]; len(us) != 1 || us[0].China == nil {
+		t.Fatalf("unexpected regions: %+v", got.Regions)
+	}
+	if got.Backup == nil || len(*got.Backup) != 1 || (*got.Backup)[0].USA == nil {
+		t.Fatalf("unexpected backup: %+v", got.Backup)
+	}
+
+	b, err := jsoninline.Marshal(got)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if string(b) != data {
+		t.Fatalf("unexpected output\n got: %s\nwant: %s", b, data)
+	}
+
+	// Top-level nesting, with errors located inside the inner containers.
+	var teams [][]*User
+	if err := json.Unmarshal([]byte(`[[`+o+`]]`), jsoninline.V(&teams)); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if len(teams) != 1 || len(teams[0]) != 1 || teams[0][0].USA == nil {
+		t.Fatalf("unexpected teams: %+v", teams)
+	}
+	_, err = jsoninline.Unmarshal[map[string][][2]User]([]byte(`{"x":[[{},{"id":"1"}]]}`))
+	var fe *jsoninline.FieldError
+	if !errors.As(err, &fe) || fe.JSONPath != `x[0][1].id` {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Arrays follow encoding/json: extra elements are dropped and missing
+	// ones zeroed.
+	pair := [2]*User{{ID: 9}, {ID: 8}}
+	if err := jsoninline.UnmarshalOptions([]byte(`[{"name":"Ann"}]`), &pair, nil); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if pair[0] == nil || pair[0].ID != 9 || pair[0].Name != "Ann" || pair[1] != nil {
+		t.Fatalf("unexpected pair: %+v", pair)
+	}
+	if err := jsoninline.UnmarshalOptions([]byte(`[{},{},{"id":3}]`), &pair, nil); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
 	}
 }
@@ -0,0 +1,58 @@
+package jsoninline
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// object is a JSON object that remembers the order in which keys were
+// added. A key keeps the position of its first insertion; setting it again
+// only replaces its value.
+type object struct {
+	keys   []string
+	values map[string]json.RawMessage
+}
+
+func (o *object) set(key string, value json.RawMessage) {
+	if o.values == nil {
+		o.values = make(map[string]json.RawMessage)
+	}
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// parseObject reads a JSON object into an ordered object, keeping member
+// values as raw JSON.
+func parseObject(data []byte) (*object, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	o := new(object)
+	if tok == nil { // null inlines nothing
+		return o, nil
+	}
+	if tok != json.Delim('{') {
+		return nil, errors.New("jsoninline: inline value is not a JSON object")
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := tok.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		o.set(key, value)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
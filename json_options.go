@@ -0,0 +1,66 @@
+package jsoninline
+
+// Options configures how values are encoded and decoded. The zero value
+// gives the default behavior. Options apply to the whole value, including
+// nested InlineMarshaler and Inline values, except that an InlineMarshaler
+// with Options of its own uses those for its value.
+type Options struct {
+	// TagName is the struct tag field names and options are read from,
+	// in the format of the json tag. The default is "json".
+	TagName string
+
+	// DirectiveTagName is the struct tag holding the inline, remain,
+	// discriminator and variant directives, which can be given there
+	// instead of in the TagName tag so that other decoders do not see
+	// them. The default is "jsoninline".
+	DirectiveTagName string
+
+	// ConflictPolicy decides which field is kept when several fields of
+	// an object encode to the same key. The default is LastWins.
+	ConflictPolicy ConflictPolicy
+
+	// DisallowUnknownFields makes decoding fail with an
+	// *UnknownFieldsError when an object has keys that neither its struct
+	// nor any of its selected inline fields consume. Decoding continues
+	// past unknown keys so that all of them are reported at once.
+	DisallowUnknownFields bool
+
+	// CaseSensitive makes decoding match keys to field names exactly.
+	// By default, as in encoding/json, a key that is no field name
+	// matches a field whose name equals it under case folding.
+	CaseSensitive bool
+
+	// UseNumber makes decoding store numbers in interface values as
+	// json.Number instead of float64, so that they keep every digit.
+	UseNumber bool
+
+	// OmitEmpty and OmitZero make encoding treat every field as if its tag
+	// had the omitempty or omitzero option.
+	OmitEmpty bool
+	OmitZero  bool
+
+	// RequireVariant makes encoding and decoding fail when the
+	// discriminator of a struct with variant fields selects none of them,
+	// instead of leaving all variants out.
+	RequireVariant bool
+}
+
+// options returns the options to use when o may be nil.
+func (o *Options) options() Options {
+	if o == nil {
+		return Options{}
+	}
+	return *o
+}
+
+// tags returns the struct tags to read plans from.
+func (o *Options) tags() tagNames {
+	tags := defaultTags
+	if o.TagName != "" {
+		tags.name = o.TagName
+	}
+	if o.DirectiveTagName != "" {
+		tags.directive = o.DirectiveTagName
+	}
+	return tags
+}
@@ -0,0 +1,171 @@
+package jsoninline_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hydrz/jsoninline"
+)
+
+type TaggedConfig struct {
+	Name   string  `cfg:"name"`
+	Port   int     `cfg:"port,omitempty" json:"ignored"`
+	Secret string  `cfg:"-"`
+	Extra  *Limits `cfg:",inline"`
+}
+
+type Limits struct {
+	MaxConns int `cfg:"max_conns"`
+}
+
+// TestOptionsTagName ensures field names and options come from the
+// configured tag.
+func TestOptionsTagName(t *testing.T) {
+	opts := &jsoninline.Options{TagName: "cfg"}
+	c := TaggedConfig{Name: "a", Secret: "s", Extra: &Limits{MaxConns: 4}}
+	b, err := jsoninline.MarshalOptions(c, opts)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if want := `{"name":"a","max_conns":4}`; string(b) != want {
+		t.Fatalf("unexpected output\n got: %s\nwant: %s", b, want)
+	}
+
+	var decoded TaggedConfig
+	if err := jsoninline.UnmarshalOptions([]byte(`{"name":"b","port":80,"max_conns":8}`), &decoded, opts); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if decoded.Name != "b" || decoded.Port != 80 || decoded.Extra == nil || decoded.Extra.MaxConns != 8 {
+		t.Fatalf("unexpected value: %+v", decoded)
+	}
+
+	// the default tag is still json
+	b, err = jsoninline.Marshal(c)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if want := `{"Name":"a","ignored":0,"Secret":"s","Extra":{"MaxConns":4}}`; string(b) != want {
+		t.Fatalf("unexpected output\n got: %s\nwant: %s", b, want)
+	}
+}
+
+// TestOptionsOmit ensures the omit rules apply to fields without the tag
+// options.
+func TestOptionsOmit(t *testing.T) {
+	u := User{ID: 1, China: &China{City: "Shenzhen"}}
+	b, err := jsoninline.MarshalOptions(u, &jsoninline.Options{OmitEmpty: true})
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if want := `{"id":1,"city":"Shenzhen"}`; string(b) != want {
+		t.Fatalf("unexpected output\n got: %s\nwant: %s", b, want)
+	}
+}
+
+// TestOptionsRequireVariant ensures a discriminator selecting no variant
+// is rejected both ways.
+func TestOptionsRequireVariant(t *testing.T) {
+	opts := &jsoninline.Options{RequireVariant: true}
+	_, err := jsoninline.MarshalOptions(DNSServerOption{Type: "quic"}, opts)
+	if err == nil || !strings.Contains(err.Error(), `"quic" selects no variant`) {
+		t.Fatalf("expected variant error, got %v", err)
+	}
+
+	var opt DNSServerOption
+	if err := jsoninline.UnmarshalOptions([]byte(`{"tag":"x"}`), &opt, opts); err == nil {
+		t.Fatal("expected an error for a missing discriminator")
+	}
+	if err := jsoninline.UnmarshalOptions([]byte(`{"type":"local","tag":"x"}`), &opt, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// TestOptionsPropagate ensures nested wrappers use the options of the
+// outer value unless they have their own.
+func TestOptionsPropagate(t *testing.T) {
+	u := User{ID: 1, China: &China{City: "Shenzhen"}, USA: &USA{City: "Austin"}}
+	doc := map[string]any{
+		"doc": Document{Version: 1, Users: jsoninline.Inline[[]User]{V: []User{u}}},
+	}
+
+	strict := &jsoninline.Options{ConflictPolicy: jsoninline.ErrorOnConflict}
+	_, err := jsoninline.MarshalOptions(doc, strict)
+	var ce *jsoninline.ConflictError
+	if !errors.As(err, &ce) {
+		t.Fatalf("expected ConflictError from nested Inline, got %v", err)
+	}
+
+	// an InlineMarshaler with its own options keeps them
+	doc["doc"] = jsoninline.InlineMarshaler{V: u, Options: &jsoninline.Options{ConflictPolicy: jsoninline.FirstWins}}
+	b, err := jsoninline.MarshalOptions(doc, strict)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if want := `{"doc":{"id":1,"name":"","email":"","city":"Shenzhen"}}`; string(b) != want {
+		t.Fatalf("unexpected output\n got: %s\nwant: %s", b, want)
+	}
+
+	var decoded Document
+	data := `{"version":1,"users":[{"id":1,"city":"Austin","nick":"a"}]}`
+	err = json.Unmarshal([]byte(data), &jsoninline.InlineMarshaler{V: &decoded, Options: &jsoninline.Options{DisallowUnknownFields: true}})
+	var ue *jsoninline.UnknownFieldsError
+	if !errors.As(err, &ue) || len(ue.Paths) != 1 || ue.Paths[0] != "users[0].nick" {
+		t.Fatalf("expected unknown users[0].nick, got %v", err)
+	}
+}
+
+type SharedConfig struct {
+	Name   string    `json:"name" yaml:"name"`
+	Limits ConnLimit `json:"limits" yaml:"limits" jsoninline:"inline" inline:"-"`
+	Mode   string    `json:"mode" yaml:"mode" jsoninline:"discriminator"`
+	Fast   *FastMode `json:"fast,omitempty" yaml:"fast" jsoninline:"inline,variant=fast"`
+}
+
+type ConnLimit struct {
+	MaxConns int `json:"max_conns" yaml:"max"`
+}
+
+type FastMode struct {
+	Burst int `json:"burst" yaml:"burst"`
+}
+
+// TestOptionsDirectiveTag ensures inline directives can live in their own
+// tag, leaving the json tag as encoding/json expects it.
+func TestOptionsDirectiveTag(t *testing.T) {
+	c := SharedConfig{Name: "a", Limits: ConnLimit{MaxConns: 4}, Mode: "fast", Fast: &FastMode{Burst: 2}}
+
+	b, err := jsoninline.Marshal(c)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if want := `{"name":"a","max_conns":4,"mode":"fast","burst":2}`; string(b) != want {
+		t.Fatalf("unexpected output\n got: %s\nwant: %s", b, want)
+	}
+	b, err = json.Marshal(c)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if want := `{"name":"a","limits":{"max_conns":4},"mode":"fast","fast":{"burst":2}}`; string(b) != want {
+		t.Fatalf("unexpected encoding/json output\n got: %s\nwant: %s", b, want)
+	}
+
+	var decoded SharedConfig
+	if err := json.Unmarshal([]byte(`{"name":"b","max_conns":8,"mode":"fast","burst":3}`), jsoninline.V(&decoded)); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if decoded.Limits.MaxConns != 8 || decoded.Fast == nil || decoded.Fast.Burst != 3 {
+		t.Fatalf("unexpected value: %+v", decoded)
+	}
+
+	// both tags can be renamed; the directive tag here keeps Limits nested
+	opts := &jsoninline.Options{TagName: "yaml", DirectiveTagName: "inline"}
+	b, err = jsoninline.MarshalOptions(c, opts)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if want := `{"name":"a","limits":{"max":4},"mode":"fast","fast":{"burst":2}}`; string(b) != want {
+		t.Fatalf("unexpected output\n got: %s\nwant: %s", b, want)
+	}
+}
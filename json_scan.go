@@ -0,0 +1,169 @@
+package jsoninline
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"unicode/utf8"
+)
+
+// rawValue is a JSON value within the input of a decode.
+type rawValue struct {
+	data []byte
+	off  int // offset of data in the input
+}
+
+// rawMember is a member of a JSON object being decoded.
+type rawMember struct {
+	key   string
+	value rawValue
+}
+
+// valueIndex records where the arrays and objects of a JSON document end,
+// so that splitting a value into its members or elements skips the nested
+// ones instead of scanning them again. Splitting every value on the way
+// down to the deepest one then takes time linear in the size of the
+// document.
+type valueIndex struct {
+	starts []int // offsets of the '[' and '{' of the document, in order
+	ends   []int // offsets just past the matching ']' and '}'
+}
+
+// indexValues indexes data, which must be valid JSON.
+func indexValues(data []byte) *valueIndex {
+	ix := new(valueIndex)
+	var open []int // indexes into starts of the values not closed yet
+	for i := 0; i < len(data); i++ {
+		switch data[i] {
+		case '"':
+			i = skipString(data, i) - 1
+		case '[', '{':
+			open = append(open, len(ix.starts))
+			ix.starts = append(ix.starts, i)
+			ix.ends = append(ix.ends, len(data))
+		case ']', '}':
+			ix.ends[open[len(open)-1]] = i + 1
+			open = open[:len(open)-1]
+		}
+	}
+	return ix
+}
+
+// end returns the offset just past the array or object starting at off.
+func (ix *valueIndex) end(off int) int {
+	i, _ := slices.BinarySearch(ix.starts, off)
+	return ix.ends[i]
+}
+
+// index returns the index of the input, building it on first use.
+func (d *decodeState) index() *valueIndex {
+	if d.ix == nil {
+		d.ix = indexValues(d.input)
+	}
+	return d.ix
+}
+
+// members returns the members of the JSON object r in document order. As
+// in encoding/json, JSON of another kind is reported as an
+// *json.UnmarshalTypeError naming t.
+func (d *decodeState) members(r rawValue, t reflect.Type) ([]rawMember, error) {
+	i := skipSpace(r.data, 0)
+	if r.data[i] != '{' {
+		return nil, parseRaw(r.data, new(map[string]json.RawMessage), t)
+	}
+	var ms []rawMember
+	for i++; ; {
+		i = skipSpace(r.data, i)
+		switch r.data[i] {
+		case '}':
+			return ms, nil
+		case ',':
+			i = skipSpace(r.data, i+1)
+		}
+		end := skipString(r.data, i)
+		key := unquoteKey(r.data[i:end])
+		i = skipSpace(r.data, skipSpace(r.data, end)+1) // past the colon
+		end = d.valueEnd(r, i)
+		ms = append(ms, rawMember{key: key, value: rawValue{data: r.data[i:end], off: r.off + i}})
+		i = end
+	}
+}
+
+// elements returns the elements of the JSON array r, reporting JSON of
+// another kind as members does.
+func (d *decodeState) elements(r rawValue, t reflect.Type) ([]rawValue, error) {
+	i := skipSpace(r.data, 0)
+	if r.data[i] != '[' {
+		return nil, parseRaw(r.data, new([]json.RawMessage), t)
+	}
+	var es []rawValue
+	for i++; ; {
+		i = skipSpace(r.data, i)
+		switch r.data[i] {
+		case ']':
+			return es, nil
+		case ',':
+			i = skipSpace(r.data, i+1)
+		}
+		end := d.valueEnd(r, i)
+		es = append(es, rawValue{data: r.data[i:end], off: r.off + i})
+		i = end
+	}
+}
+
+// valueEnd returns the offset in r just past the value starting at i.
+func (d *decodeState) valueEnd(r rawValue, i int) int {
+	switch r.data[i] {
+	case '"':
+		return skipString(r.data, i)
+	case '[', '{':
+		return d.index().end(r.off+i) - r.off
+	}
+	for ; i < len(r.data); i++ {
+		switch r.data[i] {
+		case ' ', '\t', '\r', '\n', ',', ']', '}':
+			return i
+		}
+	}
+	return i
+}
+
+// skipString returns the offset just past the string starting at off in
+// data.
+func skipString(data []byte, off int) int {
+	for i := off + 1; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return len(data)
+}
+
+// skipSpace returns the offset of the first byte at or after off in data
+// that is not white space.
+func skipSpace(data []byte, off int) int {
+	for off < len(data) {
+		switch data[off] {
+		case ' ', '\t', '\r', '\n':
+			off++
+		default:
+			return off
+		}
+	}
+	return off
+}
+
+// unquoteKey returns the object key written as the JSON string b.
+func unquoteKey(b []byte) string {
+	s := b[1 : len(b)-1]
+	if bytes.IndexByte(s, '\\') < 0 && utf8.Valid(s) {
+		return string(s)
+	}
+	var key string
+	json.Unmarshal(b, &key)
+	return key
+}
@@ -1,23 +1,13 @@
 package jsoninline
 
 import (
+	"encoding/json"
 	"reflect"
 	"slices"
 
-	_ "unsafe"
-
 	"github.com/google/jsonschema-go/jsonschema"
 )
 
-type jsonInfo struct {
-	omit     bool            // unexported or first tag element is "-"
-	name     string          // Go field name or first tag element. Empty if omit is true.
-	settings map[string]bool // "omitempty", "omitzero", etc.
-}
-
-//go:linkname fieldJSONInfo github.com/google/jsonschema-go/jsonschema.fieldJSONInfo
-func fieldJSONInfo(f reflect.StructField) jsonInfo
-
 func For[T any](opts *jsonschema.ForOptions) (*jsonschema.Schema, error) {
 	t := reflect.TypeFor[T]()
 	return ForType(t, opts)
@@ -29,59 +19,143 @@ func ForType(t reflect.Type, opts *jsonschema.ForOptions) (*jsonschema.Schema, e
 		return nil, err
 	}
 
-	if err := handleInline(t, schema); err != nil {
+	if err := handleInline(t, schema, opts); err != nil {
 		return nil, err
 	}
 
 	return schema, nil
 }
 
-func handleInline(t reflect.Type, schema *jsonschema.Schema) error {
+// handleInline rewrites the struct schemas inside schema so that their
+// properties match the fields jsoninline encodes and decodes. Inline fields
+// become anyOf alternatives, and variants become oneOf alternatives that
+// pin the discriminator to their value.
+func handleInline(t reflect.Type, schema *jsonschema.Schema, opts *jsonschema.ForOptions) error {
 	for t.Kind() == reflect.Pointer {
 		t = t.Elem()
 	}
+	if schema == nil {
+		return nil
+	}
 
 	switch t.Kind() {
 	case reflect.Array, reflect.Slice:
 		elemType := t.Elem()
-		return handleInline(elemType, schema.Items)
+		return handleInline(elemType, schema.Items, opts)
+	case reflect.Map:
+		return handleInline(t.Elem(), schema.AdditionalProperties, opts)
 	case reflect.Struct:
-		var anyOf []*jsonschema.Schema
+		if w, ok := reflect.Zero(t).Interface().(interface{ valueType() reflect.Type }); ok {
+			// Inline[T] is encoded as its value
+			s, err := ForType(w.valueType(), opts)
+			if err != nil {
+				return err
+			}
+			*schema = *s
+			return nil
+		}
+		// types with a schema of their own, such as time.Time
+		if schema.Type != "object" && !slices.Contains(schema.Types, "object") {
+			return nil
+		}
+
+		fs, err := cachedTypeFields(t, defaultTags)
+		if err != nil {
+			return err
+		}
 
-		for _, field := range reflect.VisibleFields(t) {
-			info := fieldJSONInfo(field)
-			if info.omit {
+		var (
+			anyOf, oneOf []*jsonschema.Schema
+			properties   = make(map[string]*jsonschema.Schema)
+			order        []string
+			required     []string
+		)
+		for _, f := range fs.list {
+			if f.remain {
+				// remain keys are whatever the other properties leave,
+				// which additional properties already allow
 				continue
 			}
-
-			propSchema, ok := schema.Properties[info.name]
+			propSchema, ok := schema.Properties[f.name]
 			if !ok {
-				continue
+				if propSchema, err = jsonschema.ForType(f.typ, opts); err != nil {
+					return err
+				}
+			}
+			if err := handleInline(f.typ, propSchema, opts); err != nil {
+				return err
+			}
+			if f.quoted && !hasCodec(f.typ) && (f.typ.Kind() != reflect.Pointer || !hasCodec(f.typ.Elem())) {
+				propSchema = quotedSchema(propSchema)
 			}
 
-			if info.settings["inline"] {
-				anyOf = append(anyOf, propSchema)
-				delete(schema.Properties, info.name)
-				schema.Required = slices.DeleteFunc(schema.Required, func(s string) bool {
-					return s == info.name
-				})
+			if f.inline {
+				if f.variant != "" {
+					oneOf = append(oneOf, variantSchema(propSchema, &fs.list[fs.discriminator], f.variant))
+				} else {
+					anyOf = append(anyOf, propSchema)
+				}
+				continue
 			}
 
-			if err := handleInline(field.Type, propSchema); err != nil {
-				return err
+			if _, ok := properties[f.name]; !ok {
+				order = append(order, f.name)
+			}
+			properties[f.name] = propSchema
+			if !f.omitEmpty && !f.omitZero && !slices.Contains(required, f.name) {
+				required = append(required, f.name)
 			}
 		}
 
+		schema.Properties = properties
+		schema.PropertyOrder = order
+		schema.Required = required
 		schema.AdditionalProperties = nil
-		if len(anyOf) > 0 {
+		if len(anyOf) > 0 || len(oneOf) > 0 {
 			cloned := schema.CloneSchemas()
 			schema.Type = ""
+			schema.Types = nil
 			schema.Properties = nil
+			schema.PropertyOrder = nil
 			schema.Required = nil
 			schema.AllOf = append(schema.AllOf, cloned)
 
-			schema.AllOf = append(schema.AllOf, &jsonschema.Schema{AnyOf: anyOf})
+			if len(anyOf) > 0 {
+				schema.AllOf = append(schema.AllOf, &jsonschema.Schema{AnyOf: anyOf})
+			}
+			if len(oneOf) > 0 {
+				schema.AllOf = append(schema.AllOf, &jsonschema.Schema{OneOf: oneOf})
+			}
 		}
 	}
 	return nil
 }
+
+// quotedSchema returns the schema of a field with the string option, whose
+// scalar value s describes is written inside a JSON string.
+func quotedSchema(s *jsonschema.Schema) *jsonschema.Schema {
+	q := &jsonschema.Schema{Title: s.Title, Description: s.Description, Type: "string"}
+	if slices.Contains(s.Types, "null") {
+		q.Type, q.Types = "", []string{"null", "string"}
+	}
+	return q
+}
+
+// variantSchema restricts the schema of a variant to objects whose
+// discriminator holds the variant's value.
+func variantSchema(schema *jsonschema.Schema, disc *field, variant string) *jsonschema.Schema {
+	var value any = variant
+	if t := disc.typ; t.Kind() != reflect.String && (t.Kind() != reflect.Pointer || t.Elem().Kind() != reflect.String) {
+		// non-string discriminators are written as JSON literals
+		_ = json.Unmarshal([]byte(variant), &value)
+	}
+	return &jsonschema.Schema{
+		AllOf: []*jsonschema.Schema{
+			schema,
+			{
+				Properties: map[string]*jsonschema.Schema{disc.name: {Const: &value}},
+				Required:   []string{disc.name},
+			},
+		},
+	}
+}
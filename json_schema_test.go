@@ -50,6 +50,17 @@ func TestSchema(t *testing.T) {
 		t.Fatalf("Failed to generate schema: %v", err)
 	}
 
+	// A struct with inline fields is the allOf of its own properties and
+	// of the anyOf of its inline fields, each of which has the same shape.
+	items := schema.Properties["users"].Items
+	if len(items.AllOf) != 2 || len(items.AllOf[1].AnyOf) != 2 {
+		t.Fatalf("unexpected schema shape for users items: %+v", items)
+	}
+	china, usa := items.AllOf[1].AnyOf[0], items.AllOf[1].AnyOf[1]
+	if len(china.AllOf) != 2 || len(usa.AllOf) != 2 {
+		t.Fatalf("unexpected schema shape for inline fields: %+v %+v", china, usa)
+	}
+
 	tests := []struct {
 		schema *jsonschema.Schema
 		props  []string
@@ -59,17 +70,25 @@ func TestSchema(t *testing.T) {
 			props:  []string{"$schema", "users"},
 		},
 		{
-			schema: schema.Properties["users"].Items,
+			schema: items.AllOf[0],
 			props:  []string{"id", "name", "email"},
 		},
 		{
-			schema: schema.Properties["users"].Items.OneOf[0],
+			schema: china.AllOf[0],
 			props:  []string{"province", "city"},
 		},
 		{
-			schema: schema.Properties["users"].Items.OneOf[1],
+			schema: usa.AllOf[0],
 			props:  []string{"state", "city"},
 		},
+		{
+			schema: china.AllOf[1].AnyOf[0],
+			props:  []string{"foo_field"},
+		},
+		{
+			schema: usa.AllOf[1].AnyOf[1],
+			props:  []string{"bar_field"},
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,129 @@
+package jsoninline
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+)
+
+// An Encoder writes JSON values with inline fields flattened to an output
+// stream, like json.Encoder.
+type Encoder struct {
+	w          io.Writer
+	opts       Options
+	prefix     string
+	indent     string
+	escapeHTML bool
+}
+
+// NewEncoder returns a new encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w, escapeHTML: true}
+}
+
+// Encode writes the JSON encoding of v to the stream, followed by a newline
+// character.
+func (enc *Encoder) Encode(v any) error {
+	e := newEncodeState(enc.opts)
+	defer e.release()
+	e.escapeHTML = enc.escapeHTML
+	if err := e.value(reflect.ValueOf(v)); err != nil {
+		return finishPath(err)
+	}
+	e.WriteByte('\n')
+
+	b := e.Bytes()
+	if enc.prefix != "" || enc.indent != "" {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, b, enc.prefix, enc.indent); err != nil {
+			return err
+		}
+		b = buf.Bytes()
+	}
+	_, err := enc.w.Write(b)
+	return err
+}
+
+// SetOptions configures the encoding of subsequent values. Nil means the
+// defaults.
+func (enc *Encoder) SetOptions(opts *Options) {
+	enc.opts = opts.options()
+}
+
+// SetIndent instructs the encoder to format each subsequent encoded value as
+// if indented by json.Indent.
+func (enc *Encoder) SetIndent(prefix, indent string) {
+	enc.prefix = prefix
+	enc.indent = indent
+}
+
+// SetEscapeHTML specifies whether problematic HTML characters should be
+// escaped inside JSON quoted strings. The default is true.
+func (enc *Encoder) SetEscapeHTML(on bool) {
+	enc.escapeHTML = on
+}
+
+// A Decoder reads JSON values from an input stream and decodes them with
+// inline fields flattened, like json.Decoder. Token and More can be used
+// to step into an array and decode its elements one at a time.
+type Decoder struct {
+	dec  *json.Decoder
+	opts Options
+}
+
+// NewDecoder returns a new decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{dec: json.NewDecoder(r)}
+}
+
+// Decode reads the next JSON value from its input and stores it in the
+// value pointed to by v.
+func (dec *Decoder) Decode(v any) error {
+	var raw json.RawMessage
+	if err := dec.dec.Decode(&raw); err != nil {
+		return err
+	}
+	return unmarshal(raw, v, dec.opts)
+}
+
+// DisallowUnknownFields causes the decoder to return an
+// *UnknownFieldsError when a value has keys that no field consumes.
+func (dec *Decoder) DisallowUnknownFields() {
+	dec.opts.DisallowUnknownFields = true
+}
+
+// UseNumber causes the decoder to unmarshal a number into an interface
+// value as a json.Number instead of as a float64.
+func (dec *Decoder) UseNumber() {
+	dec.opts.UseNumber = true
+}
+
+// SetOptions configures the decoding of subsequent values, replacing any
+// earlier settings. Nil means the defaults.
+func (dec *Decoder) SetOptions(opts *Options) {
+	dec.opts = opts.options()
+}
+
+// More reports whether there is another element in the current array or
+// object being parsed.
+func (dec *Decoder) More() bool {
+	return dec.dec.More()
+}
+
+// Token returns the next JSON token in the input stream, as
+// json.Decoder.Token does.
+func (dec *Decoder) Token() (json.Token, error) {
+	return dec.dec.Token()
+}
+
+// Buffered returns a reader of the data remaining in the decoder's buffer.
+func (dec *Decoder) Buffered() io.Reader {
+	return dec.dec.Buffered()
+}
+
+// InputOffset returns the input stream byte offset of the current decoder
+// position.
+func (dec *Decoder) InputOffset() int64 {
+	return dec.dec.InputOffset()
+}
@@ -0,0 +1,83 @@
+package jsoninline_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hydrz/jsoninline"
+)
+
+// TestEncoder ensures the encoder flattens inline fields and honors the
+// indentation and HTML escaping settings of json.Encoder.
+func TestEncoder(t *testing.T) {
+	u := User{ID: 1, Name: "<Alice & Bob>", China: &China{City: "Shenzhen"}}
+
+	var buf bytes.Buffer
+	enc := jsoninline.NewEncoder(&buf)
+	if err := enc.Encode(u); err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(map[string]any{"<user>": u, "raw": json.RawMessage(`"<b>"`)}); err != nil {
+		t.Fatalf("encode failed: %v", err)
+	}
+
+	want := `{"id":1,"name":"\u003cAlice \u0026 Bob\u003e","email":"","city":"Shenzhen"}
+{
+  "<user>": {
+    "id": 1,
+    "name": "<Alice & Bob>",
+    "email": "",
+    "city": "Shenzhen"
+  },
+  "raw": "<b>"
+}
+`
+	if buf.String() != want {
+		t.Fatalf("unexpected output\n got: %s\nwant: %s", buf.String(), want)
+	}
+}
+
+// TestDecoderStream ensures the elements of an array can be decoded one at
+// a time.
+func TestDecoderStream(t *testing.T) {
+	input := `[
+        {"type":"local","tag":"local-dns","prefer_go":true},
+        {"type":"udp","tag":"udp-dns","server":"1.1.1.1","server_port":53}
+    ]
+    {"type":"tls","tag":"tls-dns","sni":"dns.google","port":853}`
+
+	dec := jsoninline.NewDecoder(strings.NewReader(input))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		t.Fatalf("expected array start, got %v %v", tok, err)
+	}
+	var got []DNSServerOption
+	for dec.More() {
+		var opt DNSServerOption
+		if err := dec.Decode(&opt); err != nil {
+			t.Fatalf("decode failed: %v", err)
+		}
+		got = append(got, opt)
+	}
+	if tok, err := dec.Token(); err != nil || tok != json.Delim(']') {
+		t.Fatalf("expected array end, got %v %v", tok, err)
+	}
+	if len(got) != 2 || !got[0].Local.PreferGO || got[1].UDP == nil || got[1].UDP.ServerPort != 53 {
+		t.Fatalf("unexpected elements: %+v", got)
+	}
+
+	dec.DisallowUnknownFields()
+	var opt DNSServerOption
+	err := dec.Decode(&opt)
+	var ue *jsoninline.UnknownFieldsError
+	if !errors.As(err, &ue) || len(ue.Paths) != 1 || ue.Paths[0] != "port" {
+		t.Fatalf("expected unknown port, got %v", err)
+	}
+	if opt.TLS == nil || opt.TLS.SNI != "dns.google" {
+		t.Fatalf("unexpected value: %+v", opt)
+	}
+}
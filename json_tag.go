@@ -0,0 +1,75 @@
+package jsoninline
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// jsonTag is a parsed struct tag in the format of encoding/json, e.g.
+// `json:"name,omitempty"`.
+type jsonTag struct {
+	name string // key set by the tag, or empty to use the Go field name
+	omit bool   // the tag is "-", so the field is skipped
+
+	omitEmpty bool
+	omitZero  bool
+	asString  bool // the string option
+	inline    bool
+	remain    bool
+}
+
+// lookupJSONTag parses the struct tag called name of sf.
+func lookupJSONTag(sf reflect.StructField, name string) jsonTag {
+	return parseJSONTag(sf.Tag.Get(name))
+}
+
+// parseJSONTag parses the value of a json struct tag. As in encoding/json,
+// "-" skips the field while "-," names it "-", names with characters that
+// cannot appear in a tag are ignored, and unknown options are ignored.
+func parseJSONTag(tag string) jsonTag {
+	if tag == "-" {
+		return jsonTag{omit: true}
+	}
+	name, opts, _ := strings.Cut(tag, ",")
+	t := jsonTag{}
+	if isValidTag(name) {
+		t.name = name
+	}
+	for opts != "" {
+		var opt string
+		opt, opts, _ = strings.Cut(opts, ",")
+		switch opt {
+		case "omitempty":
+			t.omitEmpty = true
+		case "omitzero":
+			t.omitZero = true
+		case "string":
+			t.asString = true
+		case "inline":
+			t.inline = true
+		case "remain":
+			t.remain = true
+		}
+	}
+	return t
+}
+
+// isValidTag reports whether s can be used as a key in a struct tag, with
+// the rules of encoding/json.
+func isValidTag(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		switch {
+		case strings.ContainsRune("!#$%&()*+-./:;<=>?@[]^_{|}~ ", c):
+			// Backslash and quote chars are reserved, but
+			// otherwise any punctuation chars are allowed
+			// in a tag name.
+		case !unicode.IsLetter(c) && !unicode.IsDigit(c):
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,61 @@
+package jsoninline
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseJSONTag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want jsonTag
+	}{
+		{``, jsonTag{}},
+		{`name`, jsonTag{name: "name"}},
+		{`-`, jsonTag{omit: true}},
+		{`-,`, jsonTag{name: "-"}},
+		{`-,omitempty`, jsonTag{name: "-", omitEmpty: true}},
+		{`,omitempty`, jsonTag{omitEmpty: true}},
+		{`name,omitzero`, jsonTag{name: "name", omitZero: true}},
+		{`name,string`, jsonTag{name: "name", asString: true}},
+		{`,inline`, jsonTag{inline: true}},
+		{`,remain`, jsonTag{remain: true}},
+		{`id,omitempty,omitzero,string`, jsonTag{name: "id", omitEmpty: true, omitZero: true, asString: true}},
+		{`name,unknown,,inline`, jsonTag{name: "name", inline: true}},
+		{`name, omitempty`, jsonTag{name: "name"}},
+		{`a-b.c:d$`, jsonTag{name: "a-b.c:d$"}},
+		{`日本語`, jsonTag{name: "日本語"}},
+		{`bad"quote,omitempty`, jsonTag{omitEmpty: true}},
+		{`back\slash`, jsonTag{}},
+	}
+	for _, tt := range tests {
+		if got := parseJSONTag(tt.tag); got != tt.want {
+			t.Errorf("parseJSONTag(%q) = %+v, want %+v", tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestLookupJSONTag(t *testing.T) {
+	type S struct {
+		A int `json:"a" yaml:"b,inline"`
+		B int `yaml:"-"`
+		C int
+	}
+	st := reflect.TypeFor[S]()
+	tests := []struct {
+		field, tag string
+		want       jsonTag
+	}{
+		{"A", "json", jsonTag{name: "a"}},
+		{"A", "yaml", jsonTag{name: "b", inline: true}},
+		{"B", "yaml", jsonTag{omit: true}},
+		{"B", "json", jsonTag{}},
+		{"C", "json", jsonTag{}},
+	}
+	for _, tt := range tests {
+		sf, _ := st.FieldByName(tt.field)
+		if got := lookupJSONTag(sf, tt.tag); got != tt.want {
+			t.Errorf("lookupJSONTag(%s, %q) = %+v, want %+v", tt.field, tt.tag, got, tt.want)
+		}
+	}
+}
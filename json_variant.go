@@ -0,0 +1,89 @@
+package jsoninline
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// inlineTag holds the directives found in a field's `jsoninline` tag, or
+// the directive tag configured by Options.DirectiveTagName.
+type inlineTag struct {
+	inline        bool   // same as the inline option of the json tag
+	remain        bool   // same as the remain option of the json tag
+	discriminator bool   // field selects which variant is active
+	variant       string // value of the discriminator that activates this inline field
+}
+
+func parseInlineTag(f reflect.StructField, name string) inlineTag {
+	var tag inlineTag
+	for _, opt := range strings.Split(f.Tag.Get(name), ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(opt), "=")
+		switch key {
+		case "inline":
+			tag.inline = true
+		case "remain":
+			tag.remain = true
+		case "discriminator":
+			tag.discriminator = true
+		case "variant":
+			tag.variant = value
+		}
+	}
+	return tag
+}
+
+// discriminatorValue formats the discriminator field f of a struct, whose
+// value is v, for comparison with variant names. The value is encoded as
+// it would be written and read back by rawDiscriminatorValue, so that
+// encoding and decoding select the same variant whatever codec the type
+// of the field has.
+func discriminatorValue(v reflect.Value, f *field, opts Options) string {
+	if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+		return ""
+	}
+	e := newEncodeState(opts)
+	defer e.release()
+	var err error
+	if f.quoted {
+		err = e.quoted(v)
+	} else {
+		err = e.value(v)
+	}
+	if err != nil {
+		// reported when the field itself is encoded
+		return ""
+	}
+	return rawDiscriminatorValue(e.Bytes())
+}
+
+// rawDiscriminatorValue formats the JSON value of a discriminator key for
+// comparison with variant names. Strings are unquoted, other literals are
+// used as written.
+func rawDiscriminatorValue(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+// checkVariant reports an error if fs, the plan of the struct type t, has
+// variant fields and none of them is selected by the discriminator value.
+func (fs *structFields) checkVariant(t reflect.Type, selected string) error {
+	hasVariant := false
+	for i := range fs.list {
+		if v := fs.list[i].variant; v != "" {
+			if v == selected {
+				return nil
+			}
+			hasVariant = true
+		}
+	}
+	if !hasVariant {
+		return nil
+	}
+	return errors.New("jsoninline: discriminator " + strconv.Quote(selected) + " selects no variant of " + t.String())
+}
@@ -0,0 +1,205 @@
+package jsoninline_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/hydrz/jsoninline"
+)
+
+type DNSServerOption struct {
+	Type  string               `json:"type" jsoninline:"discriminator"`
+	Tag   string               `json:"tag"`
+	Local LocalDNSServerOption `json:",inline" jsoninline:"variant=local"`
+	UDP   *UDPDNSServerOption  `json:",inline" jsoninline:"variant=udp"`
+	TLS   *TLSDNSServerOption  `json:",inline" jsoninline:"variant=tls"`
+}
+
+type LocalDNSServerOption struct {
+	PreferGO bool `json:"prefer_go,omitempty"`
+}
+
+type UDPDNSServerOption struct {
+	Server     string `json:"server,omitempty"`
+	ServerPort int    `json:"server_port,omitempty"`
+}
+
+type TLSDNSServerOption struct {
+	Server     string `json:"server,omitempty"`
+	ServerPort int    `json:"server_port,omitempty"`
+	SNI        string `json:"sni,omitempty"`
+}
+
+// TestMarshalVariant ensures only the inline field selected by the
+// discriminator is emitted, even when other variants are set.
+func TestMarshalVariant(t *testing.T) {
+	opt := DNSServerOption{
+		Type:  "udp",
+		Tag:   "udp-dns",
+		Local: LocalDNSServerOption{PreferGO: true},
+		UDP:   &UDPDNSServerOption{Server: "1.1.1.1", ServerPort: 53},
+		TLS:   &TLSDNSServerOption{Server: "dns.google", ServerPort: 853, SNI: "dns.google"},
+	}
+
+	b, err := json.Marshal(jsoninline.V(opt))
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatalf("failed to parse marshaled JSON: %v -- %s", err, string(b))
+	}
+	if got, _ := m["server"].(string); got != "1.1.1.1" {
+		t.Fatalf("expected server=1.1.1.1, got %v, raw: %s", m["server"], string(b))
+	}
+	for _, key := range []string{"prefer_go", "sni"} {
+		if _, ok := m[key]; ok {
+			t.Fatalf("did not expect %q in output: %s", key, string(b))
+		}
+	}
+}
+
+// TestUnmarshalVariant ensures only the inline field selected by the
+// discriminator is populated on decode.
+func TestUnmarshalVariant(t *testing.T) {
+	data := `[
+        {"type":"local","tag":"local-dns","prefer_go":true},
+        {"type":"udp","tag":"udp-dns","server":"1.1.1.1","server_port":53},
+        {"type":"tls","tag":"tls-dns","server":"dns.google","server_port":853,"sni":"dns.google"}
+    ]`
+
+	var opts []DNSServerOption
+	if err := json.Unmarshal([]byte(data), jsoninline.V(&opts)); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if len(opts) != 3 {
+		t.Fatalf("expected 3 options, got %d", len(opts))
+	}
+
+	if !opts[0].Local.PreferGO || opts[0].UDP != nil || opts[0].TLS != nil {
+		t.Fatalf("unexpected local option: %+v", opts[0])
+	}
+	if opts[1].UDP == nil || opts[1].UDP.Server != "1.1.1.1" || opts[1].UDP.ServerPort != 53 {
+		t.Fatalf("unexpected udp variant: %+v", opts[1].UDP)
+	}
+	if opts[1].TLS != nil {
+		t.Fatalf("expected TLS unset for udp option, got %+v", opts[1].TLS)
+	}
+	if opts[2].TLS == nil || opts[2].TLS.SNI != "dns.google" || opts[2].UDP != nil {
+		t.Fatalf("unexpected tls option: %+v", opts[2])
+	}
+}
+
+// TestVariantWithoutDiscriminator ensures a variant declared without a
+// discriminator field is reported instead of silently ignored.
+func TestVariantWithoutDiscriminator(t *testing.T) {
+	type Broken struct {
+		UDP *UDPDNSServerOption `json:",inline" jsoninline:"variant=udp"`
+	}
+
+	_, err := json.Marshal(jsoninline.V(Broken{}))
+	if err == nil || !strings.Contains(err.Error(), "without a discriminator") {
+		t.Fatalf("expected missing discriminator error, got %v", err)
+	}
+}
+
+// TestSchemaVariant ensures the schema pins each variant to its
+// discriminator value.
+func TestSchemaVariant(t *testing.T) {
+	schema, err := jsoninline.For[DNSServerOption](&jsonschema.ForOptions{})
+	if err != nil {
+		t.Fatalf("Failed to generate schema: %v", err)
+	}
+	resolved, err := schema.Resolve(&jsonschema.ResolveOptions{})
+	if err != nil {
+		t.Fatalf("Failed to resolve schema: %v", err)
+	}
+
+	tests := []struct {
+		data  string
+		valid bool
+	}{
+		{`{"type":"udp","tag":"udp-dns","server":"1.1.1.1","server_port":53}`, true},
+		{`{"type":"local","tag":"local-dns","prefer_go":true}`, true},
+		{`{"type":"quic","tag":"quic-dns"}`, false},
+		{`{"tag":"untyped"}`, false},
+	}
+	for _, tt := range tests {
+		var v any
+		if err := json.Unmarshal([]byte(tt.data), &v); err != nil {
+			t.Fatalf("bad test data %s: %v", tt.data, err)
+		}
+		err := resolved.Validate(v)
+		if tt.valid && err != nil {
+			t.Errorf("expected %s to be valid, got %v", tt.data, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("expected %s to be rejected", tt.data)
+		}
+	}
+}
+
+// Protocol is a discriminator with its own JSON encoding.
+type Protocol int
+
+const (
+	ProtocolTCP Protocol = iota
+	ProtocolUDP
+)
+
+func (p Protocol) MarshalJSON() ([]byte, error) {
+	if p == ProtocolUDP {
+		return []byte(`"udp"`), nil
+	}
+	return []byte(`"tcp"`), nil
+}
+
+func (p *Protocol) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	*p = ProtocolTCP
+	if s == "udp" {
+		*p = ProtocolUDP
+	}
+	return nil
+}
+
+type ProtocolOption struct {
+	Protocol Protocol            `json:"protocol" jsoninline:"discriminator"`
+	UDP      *UDPDNSServerOption `json:",inline" jsoninline:"variant=udp"`
+}
+
+// TestVariantMarshalerDiscriminator ensures a discriminator with its own
+// MarshalJSON selects the variant named by its JSON value both ways.
+func TestVariantMarshalerDiscriminator(t *testing.T) {
+	opt := ProtocolOption{Protocol: ProtocolUDP, UDP: &UDPDNSServerOption{Server: "1.1.1.1"}}
+	b, err := jsoninline.Marshal(opt)
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	if want := `{"protocol":"udp","server":"1.1.1.1"}`; string(b) != want {
+		t.Fatalf("got %s, want %s", b, want)
+	}
+
+	got, err := jsoninline.Unmarshal[ProtocolOption](b)
+	if err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if got.Protocol != ProtocolUDP || got.UDP == nil || got.UDP.Server != "1.1.1.1" {
+		t.Fatalf("round trip lost the variant: %+v", got)
+	}
+
+	// without the key, the variant of the value decoded into is kept
+	into := ProtocolOption{Protocol: ProtocolUDP}
+	if err := json.Unmarshal([]byte(`{"server":"8.8.8.8"}`), jsoninline.V(&into)); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if into.UDP == nil || into.UDP.Server != "8.8.8.8" {
+		t.Fatalf("expected udp variant to be decoded, got %+v", into)
+	}
+}